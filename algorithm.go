@@ -0,0 +1,47 @@
+package omnibor
+
+import (
+	"fmt"
+)
+
+// HashAlgorithm names the hash function gitoids are computed with.
+type HashAlgorithm string
+
+const (
+	// Sha1 computes gitoids like a classic git repository, as 40 hex digits.
+	Sha1 HashAlgorithm = "sha1"
+
+	// Sha256 computes gitoids like a git repository using the sha256 object format, as 64 hex digits.
+	Sha256 HashAlgorithm = "sha256"
+)
+
+// The identities of an OmniBOR document without references, the gitoid of the empty blob.
+// Identity returns them for a tree that is empty, see ArtifactTree.IsEmpty.
+const (
+	EmptySha1Identity   = "e69de29bb2d1d6434b8b29ae775ad8c2e48c5391"
+	EmptySha256Identity = "473a0f4c3be8a93681a267e3b1e9a7dcda1185436fe141f7749120a303721813"
+)
+
+// HexLength returns the number of hex digits of a gitoid computed with algo, or 0 for an unknown algorithm.
+func (algo HashAlgorithm) HexLength() int {
+	switch algo {
+	case Sha1:
+		return 40
+	case Sha256:
+		return 64
+	default:
+		return 0
+	}
+}
+
+// newTreeForAlgorithm returns an empty tree using algo.
+func newTreeForAlgorithm(algo HashAlgorithm) (*omniBor, error) {
+	switch algo {
+	case Sha1:
+		return NewSha1OmniBOR().(*omniBor), nil
+	case Sha256:
+		return NewSha256OmniBOR().(*omniBor), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownAlgorithm, algo)
+	}
+}
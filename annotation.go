@@ -0,0 +1,117 @@
+package omnibor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Annotations are an extension of the OmniBOR document format carrying provenance next to a gitoid, such as
+// the file mode or a path hint. They are rendered as space separated key=value fields at the end of a reference
+// line, after the bom field if there is one, sorted by key:
+//
+//	blob <identity> [bom <identity>] [key=value ...]
+//
+// Keys start with a letter or digit and hold letters, digits, '.', '_' and '-'. Values are non-empty and hold
+// printable ASCII characters other than space. An annotated reference renders differently from a plain one,
+// so annotations change the identity of the tree; tools not implementing the extension reject such documents.
+
+// NewAnnotatedReference is NewReference for a reference carrying annotations, see Annotations.
+// An error wrapping ErrInvalidAnnotation is returned if a key or value cannot be rendered.
+// annotations is copied, an empty map yields a plain reference.
+func NewAnnotatedReference(identity string, bom Identifier, annotations map[string]string) (Reference, error) {
+	ref, err := NewReference(identity, bom)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateAnnotations(annotations); err != nil {
+		return nil, err
+	}
+	r := ref.(reference)
+	r.annotations = copyAnnotations(annotations)
+	return r, nil
+}
+
+func validateAnnotations(annotations map[string]string) error {
+	for key, value := range annotations {
+		if !validAnnotationKey(key) {
+			return fmt.Errorf("%w: key %q", ErrInvalidAnnotation, key)
+		}
+		if !validAnnotationValue(value) {
+			return fmt.Errorf("%w: value %q of %s", ErrInvalidAnnotation, value, key)
+		}
+	}
+	return nil
+}
+
+func validAnnotationKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		alnum := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+		if !alnum && (i == 0 || c != '.' && c != '_' && c != '-') {
+			return false
+		}
+	}
+	return true
+}
+
+func validAnnotationValue(value string) bool {
+	if value == "" {
+		return false
+	}
+	for i := 0; i < len(value); i++ {
+		if value[i] <= ' ' || value[i] >= 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// copyAnnotations returns a copy of annotations, nil if there are none.
+func copyAnnotations(annotations map[string]string) map[string]string {
+	if len(annotations) == 0 {
+		return nil
+	}
+	result := make(map[string]string, len(annotations))
+	for key, value := range annotations {
+		result[key] = value
+	}
+	return result
+}
+
+// renderAnnotations returns the annotation fields of a reference line, each preceded by a space.
+func renderAnnotations(annotations map[string]string) string {
+	keys := make([]string, 0, len(annotations))
+	for key := range annotations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for _, key := range keys {
+		sb.WriteString(" " + key + "=" + annotations[key])
+	}
+	return sb.String()
+}
+
+// parseAnnotations parses the key=value fields at the end of a reference line.
+func parseAnnotations(fields []string) (map[string]string, error) {
+	annotations := make(map[string]string, len(fields))
+	for _, field := range fields {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: unexpected field %q", ErrMalformedReference, field)
+		}
+		if _, duplicate := annotations[key]; duplicate {
+			return nil, fmt.Errorf("%w: duplicate annotation %q", ErrMalformedReference, key)
+		}
+		annotations[key] = value
+	}
+	if err := validateAnnotations(annotations); err != nil {
+		return nil, err
+	}
+	return copyAnnotations(annotations), nil
+}
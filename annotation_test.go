@@ -0,0 +1,84 @@
+package omnibor
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnnotatedReference(t *testing.T) {
+	bom, err := NewIdentifier("dc0be356e8c2ba26e66448d97db76ad050206574")
+	require.NoError(t, err)
+
+	annotations := map[string]string{"path": "src/main.c", "mode": "100755"}
+	ref, err := NewAnnotatedReference("23294b0610492cf55c1c4835216f20d376a287dd", bom, annotations)
+	require.NoError(t, err)
+	assert.Equal(t, "blob 23294b0610492cf55c1c4835216f20d376a287dd bom dc0be356e8c2ba26e66448d97db76ad050206574 "+
+		"mode=100755 path=src/main.c\n", ref.String())
+
+	// the reference keeps its own copy
+	annotations["mode"] = "100644"
+	ref.Annotations()["path"] = "changed"
+	assert.Equal(t, map[string]string{"path": "src/main.c", "mode": "100755"}, ref.Annotations())
+
+	plain, err := NewAnnotatedReference("b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\n", plain.String())
+	assert.Nil(t, plain.Annotations())
+
+	for _, invalid := range []map[string]string{
+		{"": "x"},
+		{"-mode": "x"},
+		{"mode=": "x"},
+		{"mode": ""},
+		{"path": "a b"},
+		{"path": "a\nb"},
+	} {
+		_, err := NewAnnotatedReference("b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0", nil, invalid)
+		assert.True(t, errors.Is(err, ErrInvalidAnnotation), "%v", invalid)
+	}
+}
+
+func TestAnnotatedReferenceRoundTrip(t *testing.T) {
+	bom, err := NewIdentifier("dc0be356e8c2ba26e66448d97db76ad050206574")
+	require.NoError(t, err)
+	linked, err := NewAnnotatedReference("23294b0610492cf55c1c4835216f20d376a287dd", bom, map[string]string{"mode": "100755"})
+	require.NoError(t, err)
+	standalone, err := NewAnnotatedReference("b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0", nil, map[string]string{"path": "a=b"})
+	require.NoError(t, err)
+
+	gb := NewSha1OmniBOR()
+	require.NoError(t, gb.AddReferences([]Reference{linked, standalone}))
+	require.NoError(t, gb.AddReference([]byte("world"), nil))
+	expected := "blob 04fea06420ca60892f73becee3614f6d023a4b7f\n" +
+		"blob 23294b0610492cf55c1c4835216f20d376a287dd bom dc0be356e8c2ba26e66448d97db76ad050206574 mode=100755\n" +
+		"blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0 path=a=b\n"
+	assert.Equal(t, expected, gb.String())
+	assert.Equal(t, len(expected), gb.Stats().DocumentBytes)
+
+	parsed, err := ParseStrict(strings.NewReader(expected))
+	require.NoError(t, err)
+	assert.Equal(t, expected, parsed.String())
+	assert.Equal(t, gb.Identity(), parsed.Identity())
+	assert.Equal(t, map[string]string{"path": "a=b"}, parsed.References()[2].Annotations())
+
+	assert.Equal(t, `{"algorithm":"sha1","identity":"`+gb.Identity()+`","references":[`+
+		`{"identity":"04fea06420ca60892f73becee3614f6d023a4b7f"},`+
+		`{"annotations":{"mode":"100755"},"bom":"dc0be356e8c2ba26e66448d97db76ad050206574","identity":"23294b0610492cf55c1c4835216f20d376a287dd"},`+
+		`{"annotations":{"path":"a=b"},"identity":"b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0"}]}`, string(gb.CanonicalJSON()))
+
+	for _, malformed := range []string{
+		"blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0 mode",
+		"blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0 mode=1 mode=2",
+		"blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0 mode=1 bom dc0be356e8c2ba26e66448d97db76ad050206574",
+		"blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0 bom",
+	} {
+		_, err := Parse(strings.NewReader(malformed + "\n"))
+		assert.True(t, errors.Is(err, ErrMalformedReference), malformed)
+	}
+	_, err = Parse(strings.NewReader("blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0 mode=\n"))
+	assert.True(t, errors.Is(err, ErrInvalidAnnotation))
+}
@@ -0,0 +1,103 @@
+package omnibor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// rename is replaced by tests to simulate a crash part way through a commit or a FileObjectStore.Put.
+var rename = os.Rename
+
+// Batch stages objects for a FileObjectStore and commits them together.
+// Objects become visible in the order they were staged, so staging every child before the document
+// referencing it guarantees readers never see a bom link to an object that has not been written yet.
+type Batch struct {
+	store   *FileObjectStore
+	objects []stagedObject
+}
+
+type stagedObject struct {
+	path    string
+	content []byte
+}
+
+// Batch returns an empty batch writing into s.
+func (s *FileObjectStore) Batch() *Batch {
+	return &Batch{
+		store: s,
+	}
+}
+
+// Put stages content under identity, nothing is written until Commit.
+func (b *Batch) Put(identity string, content []byte) error {
+	objectPath, err := b.store.path(identity)
+	if err != nil {
+		return err
+	}
+	b.objects = append(b.objects, stagedObject{path: objectPath, content: content})
+	return nil
+}
+
+// Len returns the number of staged objects.
+func (b *Batch) Len() int {
+	return len(b.objects)
+}
+
+// Commit writes every staged object to a temporary file next to its final path,
+// then renames them into place in the order they were staged.
+// When a temporary file cannot be written no object is made visible.
+// Temporary files left over by a failed commit are removed and the batch is emptied either way.
+func (b *Batch) Commit() error {
+	objects := b.objects
+	b.objects = nil
+
+	temps := make([]string, 0, len(objects))
+	defer func() {
+		for _, temp := range temps {
+			_ = os.Remove(temp)
+		}
+	}()
+
+	for _, obj := range objects {
+		temp, err := writeTemp(obj.path, obj.content)
+		if err != nil {
+			return err
+		}
+		temps = append(temps, temp)
+	}
+
+	for i, obj := range objects {
+		if err := rename(temps[i], obj.path); err != nil {
+			return err
+		}
+	}
+	temps = nil
+	return nil
+}
+
+// writeTemp writes content to a new temporary file in the directory of path and returns its name.
+func writeTemp(path string, content []byte) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), ".tmp-"+filepath.Base(path)+"-*")
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
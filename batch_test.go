@@ -0,0 +1,103 @@
+package omnibor
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stageTwoLevelTree stages a child tree followed by a parent tree linking to it.
+func stageTwoLevelTree(t *testing.T, batch *Batch) (ArtifactTree, ArtifactTree) {
+	child := NewSha1OmniBOR()
+	require.NoError(t, child.AddReference([]byte("hello"), nil))
+	parent := NewSha1OmniBOR()
+	require.NoError(t, parent.AddReference([]byte("world"), child))
+
+	require.NoError(t, batch.Put(child.Identity(), []byte(child.String())))
+	require.NoError(t, batch.Put(parent.Identity(), []byte(parent.String())))
+	return child, parent
+}
+
+// listFiles returns every file below dir.
+func listFiles(t *testing.T, dir string) []string {
+	var files []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return nil
+	}
+	require.NoError(t, err)
+	return files
+}
+
+func TestBatchCommit(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileObjectStore(dir)
+
+	batch := store.Batch()
+	child, parent := stageTwoLevelTree(t, batch)
+	assert.Equal(t, 2, batch.Len())
+	assert.False(t, store.Has(child.Identity()))
+	assert.False(t, store.Has(parent.Identity()))
+
+	require.NoError(t, batch.Commit())
+	assert.Equal(t, 0, batch.Len())
+	assert.Len(t, listFiles(t, dir), 2)
+
+	content, err := store.Get(parent.Identity())
+	assert.NoError(t, err)
+	assert.Equal(t, parent.String(), string(content))
+
+	path, err := SliceTo(store, parent.Identity(), "b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{parent.Identity(), child.Identity()}, path)
+}
+
+func TestBatchInvalidIdentity(t *testing.T) {
+	batch := NewFileObjectStore(t.TempDir()).Batch()
+	err := batch.Put("b6fc4c", []byte("hello"))
+	assert.True(t, errors.Is(err, ErrInvalidHashLength))
+	assert.Equal(t, 0, batch.Len())
+}
+
+func TestBatchCrash(t *testing.T) {
+	errCrash := errors.New("crash")
+	defer func() {
+		rename = os.Rename
+	}()
+
+	for renamed := 0; renamed < 2; renamed++ {
+		dir := t.TempDir()
+		store := NewFileObjectStore(dir)
+		batch := store.Batch()
+		child, parent := stageTwoLevelTree(t, batch)
+
+		calls := 0
+		rename = func(from, to string) error {
+			if calls == renamed {
+				return errCrash
+			}
+			calls++
+			return os.Rename(from, to)
+		}
+
+		err := batch.Commit()
+		assert.True(t, errors.Is(err, errCrash))
+
+		// the parent is never visible without its child and no temporary file is left behind
+		assert.False(t, store.Has(parent.Identity()))
+		assert.Equal(t, renamed == 1, store.Has(child.Identity()))
+		assert.Len(t, listFiles(t, dir), renamed)
+	}
+}
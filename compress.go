@@ -0,0 +1,43 @@
+package omnibor
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+)
+
+// gzipMagic starts every gzip stream. An OmniBOR document starts with an object kind (blob, tree, commit, tag) or "#",
+// so the two cannot be confused.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+func (srv *omniBor) WriteCompressed(w io.Writer) error {
+	zw := gzip.NewWriter(w)
+	if _, err := io.WriteString(zw, srv.String()); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// ParseCompressed reads a gzip compressed OmniBOR document, as written by WriteCompressed, and parses it as Parse does.
+func ParseCompressed(r io.Reader) (ArtifactTree, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return Parse(zr)
+}
+
+// decompressObject returns the document held by content, which is either a plain OmniBOR document
+// or one compressed by WriteCompressed.
+func decompressObject(content []byte) ([]byte, error) {
+	if !bytes.HasPrefix(content, gzipMagic) {
+		return content, nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
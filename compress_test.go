@@ -0,0 +1,61 @@
+package omnibor
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteCompressed(t *testing.T) {
+	bom, err := NewIdentifier("dc0be356e8c2ba26e66448d97db76ad050206574")
+	require.NoError(t, err)
+
+	gb := NewSha1OmniBOR()
+	assert.NoError(t, gb.AddReference([]byte("hello"), nil))
+	assert.NoError(t, gb.AddReference([]byte("world"), nil))
+	assert.NoError(t, gb.AddReference([]byte("hello2"), bom))
+
+	var buf bytes.Buffer
+	require.NoError(t, gb.WriteCompressed(&buf))
+	assert.True(t, bytes.HasPrefix(buf.Bytes(), gzipMagic))
+
+	zr, err := gzip.NewReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	plain, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, gb.String(), string(plain))
+
+	parsed, err := ParseCompressed(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, gb.String(), parsed.String())
+	assert.Equal(t, gb.Identity(), parsed.Identity())
+
+	_, err = ParseCompressed(bytes.NewReader([]byte(gb.String())))
+	assert.Error(t, err)
+}
+
+func TestCompressedObjects(t *testing.T) {
+	child := NewSha1OmniBOR()
+	assert.NoError(t, child.AddReference([]byte("hello"), nil))
+	assert.NoError(t, child.AddReference([]byte("world"), nil))
+	root := NewSha1OmniBOR()
+	assert.NoError(t, root.AddReference([]byte("hello2"), child))
+
+	store := NewFileObjectStore(t.TempDir(), WithVerifyOnRead())
+	for _, gb := range []ArtifactTree{child, root} {
+		var buf bytes.Buffer
+		require.NoError(t, gb.WriteCompressed(&buf))
+		require.NoError(t, store.Put(gb.Identity(), buf.Bytes()))
+	}
+
+	_, err := store.Get(child.Identity())
+	assert.NoError(t, err)
+
+	path, err := SliceTo(store, root.Identity(), "b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0")
+	require.NoError(t, err)
+	assert.Equal(t, []string{root.Identity(), child.Identity()}, path)
+}
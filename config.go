@@ -0,0 +1,60 @@
+package omnibor
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// Config describes an ArtifactTree declaratively, as an alternative to a constructor and its options.
+// The zero value describes the tree returned by NewSha1OmniBOR.
+type Config struct {
+	// Algorithm is the hash algorithm of the tree, Sha1 if empty.
+	Algorithm HashAlgorithm
+
+	// Comparator orders References and Walk, see WithComparator. nil keeps the canonical order.
+	Comparator func(r1, r2 Reference) bool
+
+	// Workers is the number of files AddTree and AddFS hash concurrently, see WithWorkers.
+	// 0 picks the default, negative values are invalid.
+	Workers int
+
+	// Exclude holds the glob patterns of files and directories AddTree and AddFS skip, see WithExclude.
+	Exclude []string
+
+	// NoFollowSymlinks makes AddTree reference symbolic links themselves, see WithFollowSymlinks.
+	NoFollowSymlinks bool
+}
+
+// NewFromConfig validates config and returns the tree it describes.
+// The ingestion settings apply to every AddTree, AddTreeContext and AddFS call, options passed to a call
+// take precedence. An error wrapping ErrInvalidConfig or ErrUnknownAlgorithm is returned for an invalid config.
+func NewFromConfig(config Config) (ArtifactTree, error) {
+	algo := config.Algorithm
+	if algo == "" {
+		algo = Sha1
+	}
+	srv, err := newTreeForAlgorithm(algo)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Workers < 0 {
+		return nil, fmt.Errorf("%w: %d workers", ErrInvalidConfig, config.Workers)
+	}
+	for _, pattern := range config.Exclude {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%w: exclude pattern %q: %v", ErrInvalidConfig, pattern, err)
+		}
+	}
+
+	srv.comparator = config.Comparator
+	srv.defaults = []Option{
+		// copied so later changes to the caller's slice do not leak into the tree
+		WithExclude(append([]string(nil), config.Exclude...)...),
+		WithFollowSymlinks(!config.NoFollowSymlinks),
+	}
+	if config.Workers > 0 {
+		srv.defaults = append(srv.defaults, WithWorkers(config.Workers))
+	}
+	return srv, nil
+}
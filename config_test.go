@@ -0,0 +1,68 @@
+package omnibor
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFromConfig(t *testing.T) {
+	gb, err := NewFromConfig(Config{})
+	require.NoError(t, err)
+	assert.Equal(t, EmptySha1Identity, gb.Identity())
+
+	root := createTree(t)
+	writeTestFile(t, root, "vendor/independent", "independent")
+	require.NoError(t, os.Symlink(filepath.Join(root, "a", "hello"), filepath.Join(root, "link")))
+
+	descending := func(r1, r2 Reference) bool {
+		return r1.Identity() > r2.Identity()
+	}
+	fromOptions := NewSha256OmniBOR(WithComparator(descending))
+	require.NoError(t, fromOptions.AddTree(root, WithExclude("vendor"), WithFollowSymlinks(false), WithWorkers(2)))
+
+	fromConfig, err := NewFromConfig(Config{
+		Algorithm:        Sha256,
+		Comparator:       descending,
+		Workers:          2,
+		Exclude:          []string{"vendor"},
+		NoFollowSymlinks: true,
+	})
+	require.NoError(t, err)
+	require.NoError(t, fromConfig.AddTree(root))
+
+	assert.Equal(t, fromOptions.String(), fromConfig.String())
+	assert.Equal(t, fromOptions.Identity(), fromConfig.Identity())
+	identities := func(gb ArtifactTree) []string {
+		result := make([]string, 0, gb.Len())
+		for _, ref := range gb.References() {
+			result = append(result, ref.Identity())
+		}
+		return result
+	}
+	// workers add files in no particular order, so only the order of the identities is compared
+	assert.Equal(t, identities(fromOptions), identities(fromConfig))
+
+	// options of a call add to the config
+	fromConfig, err = NewFromConfig(Config{Exclude: []string{"vendor"}})
+	require.NoError(t, err)
+	require.NoError(t, fromConfig.AddTree(root, WithExclude("b")))
+	assert.False(t, fromConfig.Contains("be78cc5602c5457f144a67e574b8f98b9dc2a1a0"))
+	assert.False(t, fromConfig.Contains("04fea06420ca60892f73becee3614f6d023a4b7f"))
+	assert.True(t, fromConfig.Clone().(*omniBor).ingestOptions(nil).followSymlinks)
+}
+
+func TestNewFromConfigInvalid(t *testing.T) {
+	_, err := NewFromConfig(Config{Algorithm: "md5"})
+	assert.True(t, errors.Is(err, ErrUnknownAlgorithm))
+
+	_, err = NewFromConfig(Config{Workers: -1})
+	assert.True(t, errors.Is(err, ErrInvalidConfig))
+
+	_, err = NewFromConfig(Config{Exclude: []string{"[vendor"}})
+	assert.True(t, errors.Is(err, ErrInvalidConfig))
+}
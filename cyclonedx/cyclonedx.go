@@ -0,0 +1,128 @@
+/*
+Package cyclonedx exports OmniBOR artifact trees as CycloneDX 1.5 JSON documents.
+
+Every reference of the tree becomes a file component identified by its gitoid URI,
+which is used both as the component name and as its bom-ref. Gitoids are not plain digests of the
+file content, so they are not listed as component hashes. A reference built from another artifact tree
+carries that tree's gitoid URI in the "omnibor:bom" property.
+
+The tree itself is described by the metadata component and the serial number is a name based (version 5) UUID
+of the tree's gitoid URI, so the same tree always produces the same document.
+*/
+package cyclonedx
+
+import (
+	"crypto/sha1" // #nosec G505 -- required by RFC 4122 name based UUIDs
+	"encoding/json"
+	"fmt"
+
+	omnibor "github.com/omnibor/omnibor-go"
+)
+
+const (
+	bomFormat   = "CycloneDX"
+	specVersion = "1.5"
+
+	// BomProperty names the component property linking a reference to the artifact tree it was built from.
+	BomProperty = "omnibor:bom"
+)
+
+type document struct {
+	BomFormat    string      `json:"bomFormat"`
+	SpecVersion  string      `json:"specVersion"`
+	SerialNumber string      `json:"serialNumber"`
+	Version      int         `json:"version"`
+	Metadata     metadata    `json:"metadata"`
+	Components   []component `json:"components"`
+}
+
+type metadata struct {
+	Component component `json:"component"`
+}
+
+type component struct {
+	Type       string     `json:"type"`
+	BomRef     string     `json:"bom-ref"`
+	Name       string     `json:"name"`
+	Properties []property `json:"properties,omitempty"`
+}
+
+type property struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// ToCycloneDX renders tree as an indented CycloneDX 1.5 JSON BOM.
+func ToCycloneDX(tree omnibor.ArtifactTree) ([]byte, error) {
+	identity := tree.Identity()
+	treeURI, err := gitoidURI(identity)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := document{
+		BomFormat:    bomFormat,
+		SpecVersion:  specVersion,
+		SerialNumber: "urn:uuid:" + uuidV5(treeURI),
+		Version:      1,
+		Metadata: metadata{
+			Component: component{
+				Type:   "file",
+				BomRef: treeURI,
+				Name:   treeURI,
+			},
+		},
+		Components: make([]component, 0),
+	}
+
+	for _, ref := range tree.References() {
+		uri, err := gitoidURI(ref.Identity())
+		if err != nil {
+			return nil, err
+		}
+		c := component{
+			Type:   "file",
+			BomRef: uri,
+			Name:   uri,
+		}
+		if ref.Bom() != nil {
+			bomURI, err := gitoidURI(ref.Bom().Identity())
+			if err != nil {
+				return nil, err
+			}
+			c.Properties = []property{{Name: BomProperty, Value: bomURI}}
+		}
+		doc.Components = append(doc.Components, c)
+	}
+
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+func gitoidURI(identity string) (string, error) {
+	switch len(identity) {
+	case 40:
+		return "gitoid:blob:sha1:" + identity, nil
+	case 64:
+		return "gitoid:blob:sha256:" + identity, nil
+	default:
+		return "", fmt.Errorf("%w: %d", omnibor.ErrInvalidHashLength, len(identity))
+	}
+}
+
+// urlNamespace is the RFC 4122 namespace for URL names.
+var urlNamespace = []byte{0x6b, 0xa7, 0xb8, 0x11, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+
+// uuidV5 returns the RFC 4122 version 5 UUID of name in the URL namespace.
+func uuidV5(name string) string {
+	h := sha1.New() // #nosec G401
+	h.Write(urlNamespace)
+	h.Write([]byte(name))
+	u := h.Sum(nil)[:16]
+	u[6] = (u[6] & 0x0f) | 0x50
+	u[8] = (u[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
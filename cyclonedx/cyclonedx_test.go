@@ -0,0 +1,48 @@
+package cyclonedx
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	omnibor "github.com/omnibor/omnibor-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestToCycloneDX(t *testing.T) {
+	leaf := omnibor.NewSha1OmniBOR()
+	require.NoError(t, leaf.AddReference([]byte("hello"), nil))
+	require.NoError(t, leaf.AddReference([]byte("world"), nil))
+
+	tree := omnibor.NewSha1OmniBOR()
+	require.NoError(t, tree.AddReference([]byte("hello2"), leaf))
+	require.NoError(t, tree.AddReference([]byte("independent"), nil))
+
+	out, err := ToCycloneDX(tree)
+	require.NoError(t, err)
+
+	golden := filepath.Join("testdata", "nested.cdx.json")
+	if *update {
+		require.NoError(t, ioutil.WriteFile(golden, out, 0644))
+	}
+	expected, err := ioutil.ReadFile(golden)
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), string(out))
+
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(out, &doc))
+	assert.Equal(t, "CycloneDX", doc["bomFormat"])
+	assert.Equal(t, "1.5", doc["specVersion"])
+	assert.Regexp(t, `^urn:uuid:[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, doc["serialNumber"])
+	assert.Len(t, doc["components"], 2)
+}
+
+func TestUUIDv5(t *testing.T) {
+	// well known value of uuid5(NAMESPACE_URL, "http://www.example.com/")
+	assert.Equal(t, "fcde3c85-2270-590f-9e7c-ee003d65e0e2", uuidV5("http://www.example.com/"))
+}
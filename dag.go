@@ -0,0 +1,68 @@
+package omnibor
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// SliceTo returns the chain of artifact tree identities leading from root to the tree that directly references target,
+// following bom links through the documents held in store.
+// The first element is always root and the last one is the tree containing target.
+// The search is breadth first, so the shortest chain is returned. Bom links whose documents are not in store are not followed.
+// It returns an error wrapping ErrReferenceNotFound if target cannot be reached.
+func SliceTo(store ObjectStore, root, target string) ([]string, error) {
+	parents := map[string]string{root: ""}
+	queue := []string{root}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		tree, err := loadTree(store, current)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ref := range tree.References() {
+			if ref.Identity() == target {
+				return slicePath(parents, current), nil
+			}
+			if ref.Bom() == nil {
+				continue
+			}
+			bom := ref.Bom().Identity()
+			if _, seen := parents[bom]; seen || !store.Has(bom) {
+				continue
+			}
+			parents[bom] = current
+			queue = append(queue, bom)
+		}
+	}
+
+	return nil, fmt.Errorf("%s from %s: %w", target, root, ErrReferenceNotFound)
+}
+
+// slicePath walks parents back from leaf to the root of the search and returns the chain root first.
+func slicePath(parents map[string]string, leaf string) []string {
+	path := make([]string, 0)
+	for current := leaf; current != ""; current = parents[current] {
+		path = append([]string{current}, path...)
+	}
+	return path
+}
+
+func loadTree(store ObjectStore, identity string) (ArtifactTree, error) {
+	content, err := store.Get(identity)
+	if err != nil {
+		return nil, err
+	}
+	content, err = decompressObject(content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", identity, err)
+	}
+	tree, err := Parse(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", identity, err)
+	}
+	return tree, nil
+}
@@ -0,0 +1,43 @@
+package omnibor
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// storeTwoLevelTree stores a leaf tree of hello and world, and a top tree referencing hello2 built from the leaf tree.
+func storeTwoLevelTree(t *testing.T, store ObjectStore) (top, leaf ArtifactTree) {
+	leaf = NewSha1OmniBOR()
+	require.NoError(t, leaf.AddReference([]byte("hello"), nil))
+	require.NoError(t, leaf.AddReference([]byte("world"), nil))
+	require.NoError(t, store.Put(leaf.Identity(), []byte(leaf.String())))
+
+	top = NewSha1OmniBOR()
+	require.NoError(t, top.AddReference([]byte("hello2"), leaf))
+	require.NoError(t, top.AddReference([]byte("independent"), nil))
+	require.NoError(t, store.Put(top.Identity(), []byte(top.String())))
+
+	return top, leaf
+}
+
+func TestSliceTo(t *testing.T) {
+	store := NewFileObjectStore(t.TempDir())
+	top, leaf := storeTwoLevelTree(t, store)
+
+	path, err := SliceTo(store, top.Identity(), "04fea06420ca60892f73becee3614f6d023a4b7f")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{top.Identity(), leaf.Identity()}, path)
+
+	path, err = SliceTo(store, top.Identity(), "23294b0610492cf55c1c4835216f20d376a287dd")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{top.Identity()}, path)
+
+	_, err = SliceTo(store, top.Identity(), "0000000000000000000000000000000000000000")
+	assert.True(t, errors.Is(err, ErrReferenceNotFound))
+
+	_, err = SliceTo(store, "0000000000000000000000000000000000000000", leaf.Identity())
+	assert.True(t, errors.Is(err, ErrObjectNotFound))
+}
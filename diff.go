@@ -0,0 +1,33 @@
+package omnibor
+
+import (
+	"fmt"
+)
+
+// Diff returns the references of b missing from a as added and those of a missing from b as removed,
+// each in the order of String. References are compared by their document line, so a reference whose bom
+// changed is both removed, with the old bom, and added, with the new one.
+// It returns an error wrapping ErrAlgorithmMismatch if a and b use different hash algorithms.
+func Diff(a, b ArtifactTree) (added, removed []Reference, err error) {
+	if len(a.Identity()) != len(b.Identity()) {
+		return nil, nil, fmt.Errorf("%w: %s and %s", ErrAlgorithmMismatch, a.Identity(), b.Identity())
+	}
+	return missing(a, b), missing(b, a), nil
+}
+
+// missing returns the references of from whose line is not in against, sorted as in String.
+func missing(against, from ArtifactTree) []Reference {
+	lines := make(map[string]bool)
+	for _, ref := range against.References() {
+		lines[ref.String()] = true
+	}
+
+	result := make([]Reference, 0)
+	for _, ref := range from.References() {
+		if !lines[ref.String()] {
+			result = append(result, ref)
+		}
+	}
+	by(referenceSorter).sort(result)
+	return result
+}
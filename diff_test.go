@@ -0,0 +1,51 @@
+package omnibor
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	bom, err := NewIdentifier("dc0be356e8c2ba26e66448d97db76ad050206574")
+	require.NoError(t, err)
+
+	a := NewSha1OmniBOR()
+	assert.NoError(t, a.AddReference([]byte("hello"), nil))
+	assert.NoError(t, a.AddReference([]byte("world"), nil))
+	assert.NoError(t, a.AddReference([]byte("hello2"), nil))
+
+	b := NewSha1OmniBOR()
+	assert.NoError(t, b.AddReference([]byte("hello"), nil))
+	assert.NoError(t, b.AddReference([]byte("hello2"), bom))
+	assert.NoError(t, b.AddReference([]byte("independent"), nil))
+
+	added, removed, err := Diff(a, b)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"blob 23294b0610492cf55c1c4835216f20d376a287dd bom dc0be356e8c2ba26e66448d97db76ad050206574\n",
+		"blob be78cc5602c5457f144a67e574b8f98b9dc2a1a0\n",
+	}, lines(added))
+	assert.Equal(t, []string{
+		"blob 04fea06420ca60892f73becee3614f6d023a4b7f\n",
+		"blob 23294b0610492cf55c1c4835216f20d376a287dd\n",
+	}, lines(removed))
+
+	added, removed, err = Diff(a, a.Clone())
+	require.NoError(t, err)
+	assert.Empty(t, added)
+	assert.Empty(t, removed)
+
+	_, _, err = Diff(a, NewSha256OmniBOR())
+	assert.True(t, errors.Is(err, ErrAlgorithmMismatch))
+}
+
+func lines(refs []Reference) []string {
+	result := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		result = append(result, ref.String())
+	}
+	return result
+}
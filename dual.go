@@ -0,0 +1,72 @@
+package omnibor
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// DualOmniBOR builds a sha1 and a sha256 artifact tree side by side, reading and hashing every input once
+// with both algorithms. Either tree's identity can then be obtained without a second pass over the inputs.
+type DualOmniBOR struct {
+	sha1   *omniBor
+	sha256 *omniBor
+}
+
+// NewDualOmniBOR creates an empty DualOmniBOR.
+func NewDualOmniBOR() *DualOmniBOR {
+	return &DualOmniBOR{
+		sha1:   NewSha1OmniBOR().(*omniBor),
+		sha256: NewSha256OmniBOR().(*omniBor),
+	}
+}
+
+// AddReference adds obj to both trees.
+// Unless bom is nil, each tree links to the tree of bom using the same algorithm.
+func (d *DualOmniBOR) AddReference(obj []byte, bom *DualOmniBOR) error {
+	return d.AddReferenceFromReader(bytes.NewReader(obj), bom, int64(len(obj)))
+}
+
+// AddReferenceFromReader adds the content of reader to both trees, reading it once, see AddReference.
+// As with ArtifactTree.AddReferenceFromReader the reader must hold exactly objLength bytes.
+func (d *DualOmniBOR) AddReferenceFromReader(reader io.Reader, bom *DualOmniBOR, objLength int64) error {
+	var bom1, bom256 Identifier
+	if bom != nil {
+		bom1, bom256 = bom.sha1, bom.sha256
+	}
+
+	id1, id256, err := hashDual(reader, objLength)
+	if err != nil {
+		return err
+	}
+
+	d.sha1.lock.Lock()
+	d.sha1.appendDistinct(reference{identity: id1, bom: bom1})
+	d.sha1.lock.Unlock()
+
+	d.sha256.lock.Lock()
+	d.sha256.appendDistinct(reference{identity: id256, bom: bom256})
+	d.sha256.lock.Unlock()
+	return nil
+}
+
+// Identity returns the identity of the tree using algo.
+func (d *DualOmniBOR) Identity(algo HashAlgorithm) (string, error) {
+	tree, err := d.Tree(algo)
+	if err != nil {
+		return "", err
+	}
+	return tree.Identity(), nil
+}
+
+// Tree returns the tree using algo. It is shared with the DualOmniBOR, not a copy.
+func (d *DualOmniBOR) Tree(algo HashAlgorithm) (ArtifactTree, error) {
+	switch algo {
+	case Sha1:
+		return d.sha1, nil
+	case Sha256:
+		return d.sha256, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownAlgorithm, algo)
+	}
+}
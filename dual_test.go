@@ -0,0 +1,128 @@
+package omnibor
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDualOmniBOR(t *testing.T) {
+	leaf := NewDualOmniBOR()
+	require.NoError(t, leaf.AddReference([]byte("hello"), nil))
+	require.NoError(t, leaf.AddReferenceFromReader(strings.NewReader("world"), nil, 5))
+	// the same content added again is listed once in both trees
+	require.NoError(t, leaf.AddReference([]byte("hello"), nil))
+
+	id, err := leaf.Identity(Sha1)
+	assert.NoError(t, err)
+	assert.Equal(t, "dc0be356e8c2ba26e66448d97db76ad050206574", id)
+	id, err = leaf.Identity(Sha256)
+	assert.NoError(t, err)
+	assert.Equal(t, "e32e7e7761709be17ef573556a82960d489ddf0092424f7db1c91d8363dde822", id)
+
+	dual := NewDualOmniBOR()
+	require.NoError(t, dual.AddReference([]byte("hello2"), leaf))
+	require.NoError(t, dual.AddReference([]byte("independent"), nil))
+
+	singles := map[HashAlgorithm]ArtifactTree{
+		Sha1:   NewSha1OmniBOR(),
+		Sha256: NewSha256OmniBOR(),
+	}
+	for algo, single := range singles {
+		bom, err := leaf.Tree(algo)
+		require.NoError(t, err)
+		require.NoError(t, single.AddReference([]byte("hello2"), bom))
+		require.NoError(t, single.AddReference([]byte("independent"), nil))
+
+		tree, err := dual.Tree(algo)
+		require.NoError(t, err)
+		assert.Equal(t, single.String(), tree.String())
+		id, err := dual.Identity(algo)
+		assert.NoError(t, err)
+		assert.Equal(t, single.Identity(), id)
+	}
+
+	_, err = dual.Identity("md5")
+	assert.True(t, errors.Is(err, ErrUnknownAlgorithm))
+}
+
+func TestDualOmniBORErrors(t *testing.T) {
+	dual := NewDualOmniBOR()
+	err := dual.AddReferenceFromReader(strings.NewReader("hello"), nil, 6)
+	assert.True(t, errors.Is(err, ErrShortRead))
+
+	for _, algo := range []HashAlgorithm{Sha1, Sha256} {
+		tree, err := dual.Tree(algo)
+		require.NoError(t, err)
+		assert.Equal(t, 0, tree.Len())
+	}
+}
+
+// largeFile creates a sparse file of the given size, cheap to create but read like any other file.
+func largeFile(t testing.TB, size int64) *os.File {
+	f, err := os.Create(filepath.Join(t.TempDir(), "large"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		f.Close()
+	})
+	require.NoError(t, f.Truncate(size))
+	return f
+}
+
+// allocatedBytes returns how many bytes fn allocated on the heap.
+func allocatedBytes(fn func()) uint64 {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	fn()
+	runtime.ReadMemStats(&after)
+	return after.TotalAlloc - before.TotalAlloc
+}
+
+func TestDualOmniBORLargeFileSinglePass(t *testing.T) {
+	const size = 64 << 20
+	f := largeFile(t, size)
+
+	dual := NewDualOmniBOR()
+	var err error
+	allocated := allocatedBytes(func() {
+		err = dual.AddReferenceFromReader(f, nil, size)
+	})
+	require.NoError(t, err)
+
+	// both gitoids are computed streaming the file once, nowhere near the file is held in memory
+	assert.Less(t, allocated, uint64(1<<20))
+
+	for _, algo := range []HashAlgorithm{Sha1, Sha256} {
+		_, err := f.Seek(0, io.SeekStart)
+		require.NoError(t, err)
+		expected, err := GitOID(f, size, algo)
+		require.NoError(t, err)
+		tree, err := dual.Tree(algo)
+		require.NoError(t, err)
+		assert.True(t, tree.Contains(expected))
+	}
+}
+
+func BenchmarkDualOmniBORLargeFile(b *testing.B) {
+	const size = 16 << 20
+	f := largeFile(b, size)
+
+	b.SetBytes(size)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			b.Fatal(err)
+		}
+		if err := NewDualOmniBOR().AddReferenceFromReader(f, nil, size); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package embed
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	omnibor "github.com/omnibor/omnibor-go"
+)
+
+// elfLayout describes where the fields EmbedELF rewrites live for a given ELF class.
+type elfLayout struct {
+	shoffOffset  int // offset of e_shoff in the file header
+	shnumOffset  int // offset of e_shnum in the file header
+	wordSize     int // size of addresses and offsets
+	shdrSize     int // size of a section header entry
+	shdrOffsetAt int // offset of sh_offset inside a section header
+	shdrSizeAt   int // offset of sh_size inside a section header
+	shdrAlignAt  int // offset of sh_addralign inside a section header
+	sectionAlign int // alignment used for the appended section header table
+}
+
+var layouts = map[elf.Class]elfLayout{
+	elf.ELFCLASS32: {
+		shoffOffset:  0x20,
+		shnumOffset:  0x30,
+		wordSize:     4,
+		shdrSize:     40,
+		shdrOffsetAt: 16,
+		shdrSizeAt:   20,
+		shdrAlignAt:  32,
+		sectionAlign: 4,
+	},
+	elf.ELFCLASS64: {
+		shoffOffset:  0x28,
+		shnumOffset:  0x3c,
+		wordSize:     8,
+		shdrSize:     64,
+		shdrOffsetAt: 24,
+		shdrSizeAt:   32,
+		shdrAlignAt:  48,
+		sectionAlign: 8,
+	},
+}
+
+// EmbedELF stores id in the .note.omnibor section of the ELF file at path.
+// An existing OmniBOR note is replaced. The section is not allocated, so it does not change the program's memory image.
+// The file is rewritten through a temporary file and renamed into place.
+func EmbedELF(path string, id omnibor.Identifier) error {
+	digest, err := hex.DecodeString(id.Identity())
+	if err != nil {
+		return err
+	}
+	var noteType uint32
+	switch len(digest) {
+	case 20:
+		noteType = NoteTypeSha1
+	case 32:
+		noteType = NoteTypeSha256
+	default:
+		return fmt.Errorf("%w: %d", omnibor.ErrInvalidHashLength, len(id.Identity()))
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	f, err := elf.NewFile(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	layout, ok := layouts[f.Class]
+	if !ok {
+		return fmt.Errorf("unsupported ELF class %s", f.Class)
+	}
+
+	content := encodeNote(f.ByteOrder, NoteName, noteType, digest)
+	if section := f.Section(SectionName); section != nil {
+		data = replaceSection(data, f, layout, sectionIndex(f, section), content)
+	} else {
+		data, err = appendSection(data, f, layout, content)
+		if err != nil {
+			return err
+		}
+	}
+
+	return writeFileAtomic(path, data)
+}
+
+func sectionIndex(f *elf.File, section *elf.Section) int {
+	for i, s := range f.Sections {
+		if s == section {
+			return i
+		}
+	}
+	return -1
+}
+
+func encodeNote(order binary.ByteOrder, name string, typ uint32, desc []byte) []byte {
+	buf := &bytes.Buffer{}
+	nameBytes := append([]byte(name), 0)
+	header := make([]byte, 12)
+	order.PutUint32(header[0:4], uint32(len(nameBytes)))
+	order.PutUint32(header[4:8], uint32(len(desc)))
+	order.PutUint32(header[8:12], typ)
+	buf.Write(header)
+	buf.Write(nameBytes)
+	buf.Write(make([]byte, align4(uint64(len(nameBytes)))-uint64(len(nameBytes))))
+	buf.Write(desc)
+	buf.Write(make([]byte, align4(uint64(len(desc)))-uint64(len(desc))))
+	return buf.Bytes()
+}
+
+// replaceSection overwrites the section at index with content.
+// If content fits in the section's current space it is written in place, otherwise it is appended to the file
+// and the section header is pointed at it.
+func replaceSection(data []byte, f *elf.File, layout elfLayout, index int, content []byte) []byte {
+	section := f.Sections[index]
+	shdr := shdrOffset(data, f, layout, index)
+
+	offset := section.Offset
+	if uint64(len(content)) > section.Size {
+		data = pad(data, 4)
+		offset = uint64(len(data))
+		data = append(data, content...)
+	} else {
+		copy(data[offset:], content)
+		for i := offset + uint64(len(content)); i < offset+section.Size; i++ {
+			data[i] = 0
+		}
+	}
+
+	putWord(f.ByteOrder, data[shdr+layout.shdrOffsetAt:], layout.wordSize, offset)
+	putWord(f.ByteOrder, data[shdr+layout.shdrSizeAt:], layout.wordSize, uint64(len(content)))
+	return data
+}
+
+// appendSection adds a new note section holding content.
+// The note data, an extended copy of the section name string table and an extended copy of the
+// section header table are appended to the file, and the file header is pointed at the new table.
+func appendSection(data []byte, f *elf.File, layout elfLayout, content []byte) ([]byte, error) {
+	shoff := readWord(f.ByteOrder, data[layout.shoffOffset:], layout.wordSize)
+	shnum := int(f.ByteOrder.Uint16(data[layout.shnumOffset:]))
+	shstrndx := int(f.ByteOrder.Uint16(data[layout.shnumOffset+2:]))
+	if shoff == 0 || shnum == 0 || shnum >= int(elf.SHN_LORESERVE) || shstrndx >= shnum {
+		return nil, fmt.Errorf("unsupported ELF section header table")
+	}
+
+	strtab := f.Sections[shstrndx]
+	names, err := strtab.Data()
+	if err != nil {
+		return nil, err
+	}
+	headers := make([]byte, shnum*layout.shdrSize)
+	copy(headers, data[shoff:shoff+uint64(len(headers))])
+
+	data = pad(data, 4)
+	noteOffset := uint64(len(data))
+	data = append(data, content...)
+
+	nameOffset := uint64(len(names))
+	names = append(append(names, SectionName...), 0)
+	strtabOffset := uint64(len(data))
+	data = append(data, names...)
+
+	// point the string table header at the extended copy
+	strtabHeader := headers[shstrndx*layout.shdrSize:]
+	putWord(f.ByteOrder, strtabHeader[layout.shdrOffsetAt:], layout.wordSize, strtabOffset)
+	putWord(f.ByteOrder, strtabHeader[layout.shdrSizeAt:], layout.wordSize, uint64(len(names)))
+
+	header := make([]byte, layout.shdrSize)
+	f.ByteOrder.PutUint32(header[0:4], uint32(nameOffset))
+	f.ByteOrder.PutUint32(header[4:8], uint32(elf.SHT_NOTE))
+	putWord(f.ByteOrder, header[layout.shdrOffsetAt:], layout.wordSize, noteOffset)
+	putWord(f.ByteOrder, header[layout.shdrSizeAt:], layout.wordSize, uint64(len(content)))
+	putWord(f.ByteOrder, header[layout.shdrAlignAt:], layout.wordSize, 4)
+	headers = append(headers, header...)
+
+	data = pad(data, layout.sectionAlign)
+	newShoff := uint64(len(data))
+	data = append(data, headers...)
+
+	putWord(f.ByteOrder, data[layout.shoffOffset:], layout.wordSize, newShoff)
+	f.ByteOrder.PutUint16(data[layout.shnumOffset:], uint16(shnum+1))
+	return data, nil
+}
+
+func shdrOffset(data []byte, f *elf.File, layout elfLayout, index int) int {
+	shoff := readWord(f.ByteOrder, data[layout.shoffOffset:], layout.wordSize)
+	return int(shoff) + index*layout.shdrSize
+}
+
+func pad(data []byte, alignment int) []byte {
+	for len(data)%alignment != 0 {
+		data = append(data, 0)
+	}
+	return data
+}
+
+func readWord(order binary.ByteOrder, b []byte, size int) uint64 {
+	if size == 4 {
+		return uint64(order.Uint32(b))
+	}
+	return order.Uint64(b)
+}
+
+func putWord(order binary.ByteOrder, b []byte, size int, v uint64) {
+	if size == 4 {
+		order.PutUint32(b, uint32(v))
+		return
+	}
+	order.PutUint64(b, v)
+}
+
+// writeFileAtomic replaces path with data, keeping its permissions.
+func writeFileAtomic(path string, data []byte) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".omnibor-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,62 @@
+/*
+Package embed reads OmniBOR identities embedded into executables.
+
+Identities are stored in an ELF note section named .note.omnibor.
+Each note is owned by "OMNIBOR", its type names the hash algorithm (NoteTypeSha1 or NoteTypeSha256)
+and its descriptor holds the raw bytes of the artifact tree's gitoid.
+*/
+package embed
+
+import (
+	"debug/elf"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	omnibor "github.com/omnibor/omnibor-go"
+)
+
+const (
+	// SectionName is the name of the ELF section holding OmniBOR notes.
+	SectionName = ".note.omnibor"
+
+	// NoteName is the owner name of OmniBOR notes.
+	NoteName = "OMNIBOR"
+
+	// NoteTypeSha1 marks a note whose descriptor is a sha1 gitoid.
+	NoteTypeSha1 = 1
+
+	// NoteTypeSha256 marks a note whose descriptor is a sha256 gitoid.
+	NoteTypeSha256 = 2
+)
+
+// ErrNotFound is returned when a binary carries no OmniBOR note.
+var ErrNotFound = errors.New("no OmniBOR identity embedded")
+
+// ExtractELF reads the OmniBOR identity embedded in the ELF file at path.
+// If the section holds several notes the first one is returned.
+func ExtractELF(path string) (omnibor.Identifier, error) {
+	f, err := elf.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	section := f.Section(SectionName)
+	if section == nil {
+		return nil, fmt.Errorf("%s: %w", path, ErrNotFound)
+	}
+	data, err := section.Data()
+	if err != nil {
+		return nil, err
+	}
+
+	notes, err := parseNotes(f.ByteOrder, data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(notes) == 0 {
+		return nil, fmt.Errorf("%s: %w", path, ErrNotFound)
+	}
+	return omnibor.NewIdentifier(hex.EncodeToString(notes[0].desc))
+}
@@ -0,0 +1,98 @@
+package embed
+
+import (
+	"debug/elf"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	omnibor "github.com/omnibor/omnibor-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// copyFixture copies the note-less ELF fixture into a temporary directory.
+func copyFixture(t *testing.T) string {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "tiny.elf"))
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "tiny.elf")
+	require.NoError(t, ioutil.WriteFile(path, data, 0755))
+	return path
+}
+
+func sectionCount(t *testing.T, path string) int {
+	f, err := elf.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	return len(f.Sections)
+}
+
+func TestExtractELFWithoutNote(t *testing.T) {
+	_, err := ExtractELF(copyFixture(t))
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestEmbedELF(t *testing.T) {
+	path := copyFixture(t)
+	sections := sectionCount(t, path)
+
+	sha1, err := omnibor.NewIdentifier("dc0be356e8c2ba26e66448d97db76ad050206574")
+	require.NoError(t, err)
+	assert.NoError(t, EmbedELF(path, sha1))
+
+	extracted, err := ExtractELF(path)
+	assert.NoError(t, err)
+	assert.Equal(t, sha1.Identity(), extracted.Identity())
+	assert.Equal(t, sections+1, sectionCount(t, path))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+}
+
+func TestEmbedELFReplacesExistingNote(t *testing.T) {
+	path := copyFixture(t)
+	sections := sectionCount(t, path)
+
+	sha1, err := omnibor.NewIdentifier("dc0be356e8c2ba26e66448d97db76ad050206574")
+	require.NoError(t, err)
+	sha256, err := omnibor.NewIdentifier("e32e7e7761709be17ef573556a82960d489ddf0092424f7db1c91d8363dde822")
+	require.NoError(t, err)
+	other, err := omnibor.NewIdentifier("8aec4e4876f854f688d0ebfc8f37598f38e5fd6903cccc850ca36591175aeb60")
+	require.NoError(t, err)
+
+	// grows the note
+	require.NoError(t, EmbedELF(path, sha1))
+	require.NoError(t, EmbedELF(path, sha256))
+	extracted, err := ExtractELF(path)
+	assert.NoError(t, err)
+	assert.Equal(t, sha256.Identity(), extracted.Identity())
+
+	// fits in place
+	size, err := os.Stat(path)
+	require.NoError(t, err)
+	require.NoError(t, EmbedELF(path, other))
+	extracted, err = ExtractELF(path)
+	assert.NoError(t, err)
+	assert.Equal(t, other.Identity(), extracted.Identity())
+
+	resized, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, size.Size(), resized.Size())
+	assert.Equal(t, sections+1, sectionCount(t, path))
+}
+
+func TestEmbedELFInvalidInput(t *testing.T) {
+	id, err := omnibor.NewIdentifier("dc0be356e8c2ba26e66448d97db76ad050206574")
+	require.NoError(t, err)
+
+	notELF := filepath.Join(t.TempDir(), "plain")
+	require.NoError(t, ioutil.WriteFile(notELF, []byte("hello"), 0644))
+	assert.Error(t, EmbedELF(notELF, id))
+
+	short, err := omnibor.NewIdentifier("dc0be3")
+	require.NoError(t, err)
+	assert.True(t, errors.Is(EmbedELF(copyFixture(t), short), omnibor.ErrInvalidHashLength))
+}
@@ -0,0 +1,63 @@
+package embed
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// descSizes maps the supported note types to the length of their digest.
+var descSizes = map[uint32]int{
+	NoteTypeSha1:   20,
+	NoteTypeSha256: 32,
+}
+
+type note struct {
+	name string
+	typ  uint32
+	desc []byte
+}
+
+// parseNotes decodes the OmniBOR entries of an ELF note section, ignoring notes owned by anyone else.
+func parseNotes(order binary.ByteOrder, data []byte) ([]note, error) {
+	notes := make([]note, 0)
+	for len(data) > 0 {
+		if len(data) < 12 {
+			return nil, fmt.Errorf("truncated note header")
+		}
+		nameSize := order.Uint32(data[0:4])
+		descSize := order.Uint32(data[4:8])
+		typ := order.Uint32(data[8:12])
+		data = data[12:]
+
+		nameEnd := align4(uint64(nameSize))
+		descEnd := nameEnd + align4(uint64(descSize))
+		if uint64(len(data)) < nameEnd+uint64(descSize) {
+			return nil, fmt.Errorf("truncated note")
+		}
+
+		name := string(data[:nameSize])
+		if nameSize > 0 && name[nameSize-1] == 0 {
+			name = name[:nameSize-1]
+		}
+		desc := data[nameEnd : nameEnd+uint64(descSize)]
+
+		if name == NoteName {
+			if size, ok := descSizes[typ]; ok {
+				if len(desc) != size {
+					return nil, fmt.Errorf("invalid note descriptor length %d for type %d", len(desc), typ)
+				}
+				notes = append(notes, note{name: name, typ: typ, desc: desc})
+			}
+		}
+
+		if uint64(len(data)) < descEnd {
+			break
+		}
+		data = data[descEnd:]
+	}
+	return notes, nil
+}
+
+func align4(n uint64) uint64 {
+	return (n + 3) &^ 3
+}
@@ -0,0 +1,47 @@
+package omnibor
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// Encoding is a textual encoding of the raw digest bytes of an identity.
+type Encoding int
+
+const (
+	// EncodingHex is lowercase hex, the form the spec mandates for documents and identities.
+	EncodingHex Encoding = iota
+
+	// EncodingBase32 is RFC 4648 base32 with the standard alphabet and without padding.
+	EncodingBase32
+
+	// EncodingBase64URL is RFC 4648 base64 with the URL and filename safe alphabet and without padding.
+	EncodingBase64URL
+)
+
+var (
+	base32Encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+	base64Encoding = base64.RawURLEncoding
+)
+
+// IdentityEncoded returns the identity with its digest bytes in the given encoding, as used by catalogs
+// keying artifacts by shorter strings. Only the hex form is an OmniBOR identity, the others cannot be
+// used in documents. Unknown encodings yield the hex form.
+func (srv *omniBor) IdentityEncoded(enc Encoding) string {
+	identity := srv.Identity()
+	digest, err := hex.DecodeString(identity)
+	if err != nil {
+		// Identity is always computed by the tree, so it is valid hex
+		panic(err)
+	}
+
+	switch enc {
+	case EncodingBase32:
+		return base32Encoding.EncodeToString(digest)
+	case EncodingBase64URL:
+		return base64Encoding.EncodeToString(digest)
+	default:
+		return identity
+	}
+}
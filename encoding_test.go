@@ -0,0 +1,36 @@
+package omnibor
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentityEncoded(t *testing.T) {
+	for _, gb := range []ArtifactTree{NewSha1OmniBOR(), NewSha256OmniBOR()} {
+		require.NoError(t, gb.AddReference([]byte("hello"), nil))
+		require.NoError(t, gb.AddReference([]byte("world"), nil))
+		digest, err := hex.DecodeString(gb.Identity())
+		require.NoError(t, err)
+
+		assert.Equal(t, gb.Identity(), gb.IdentityEncoded(EncodingHex))
+		assert.Equal(t, gb.Identity(), gb.IdentityEncoded(Encoding(42)))
+
+		decoded, err := base32Encoding.DecodeString(gb.IdentityEncoded(EncodingBase32))
+		require.NoError(t, err)
+		assert.Equal(t, digest, decoded)
+
+		decoded, err = base64Encoding.DecodeString(gb.IdentityEncoded(EncodingBase64URL))
+		require.NoError(t, err)
+		assert.Equal(t, digest, decoded)
+		assert.Less(t, len(gb.IdentityEncoded(EncodingBase64URL)), len(gb.IdentityEncoded(EncodingBase32)))
+	}
+
+	gb := NewSha1OmniBOR()
+	require.NoError(t, gb.AddReference([]byte("hello"), nil))
+	require.NoError(t, gb.AddReference([]byte("world"), nil))
+	assert.Equal(t, "3QF6GVXIYK5CNZTEJDMX3N3K2BICAZLU", gb.IdentityEncoded(EncodingBase32))
+	assert.Equal(t, "3AvjVujCuibmZEjZfbdq0FAgZXQ", gb.IdentityEncoded(EncodingBase64URL))
+}
@@ -0,0 +1,169 @@
+package omnibor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrInvalidHashLength is returned when an identity does not have the length of a sha1 or sha256 hex digest.
+	ErrInvalidHashLength = errors.New("invalid hash length")
+
+	// ErrInvalidHex is returned when an identity is not a valid hex string.
+	ErrInvalidHex = errors.New("invalid hex")
+
+	// ErrAlgorithmMismatch is returned when an identity was computed with a different hash algorithm than the tree uses.
+	ErrAlgorithmMismatch = errors.New("hash algorithm mismatch")
+
+	// ErrUnknownAlgorithm is returned when a HashAlgorithm is neither Sha1 nor Sha256.
+	ErrUnknownAlgorithm = errors.New("unknown hash algorithm")
+
+	// ErrInvalidRange is returned when a byte range has a negative start or length, or content a negative length.
+	ErrInvalidRange = errors.New("invalid range")
+
+	// ErrContentLengthMismatch is matched by every ContentLengthError, whether the content was too short or too long.
+	ErrContentLengthMismatch = errors.New("content length mismatch")
+
+	// ErrShortRead is wrapped by a ContentLengthError when a reader ends before the stated content length was read.
+	ErrShortRead = errors.New("short read")
+
+	// ErrLongRead is wrapped by a ContentLengthError when a reader holds more bytes than the stated content length.
+	ErrLongRead = errors.New("long read")
+
+	// ErrMalformedReference is returned when a line of an OmniBOR document is not a valid reference.
+	ErrMalformedReference = errors.New("malformed reference")
+
+	// ErrMalformedURI is returned by NewIdentifier when a gitoid URI is not of the form gitoid:blob:<algorithm>:<hash>.
+	ErrMalformedURI = errors.New("malformed gitoid URI")
+
+	// ErrUnknownField is wrapped by a FieldError when a JSON document holds a field that is not part of the format.
+	ErrUnknownField = errors.New("unknown field")
+
+	// ErrMissingField is wrapped by a FieldError when a JSON document lacks a required field.
+	ErrMissingField = errors.New("missing field")
+
+	// ErrUnknownObjectKind is returned when an ObjectKind is not one of the git object types.
+	ErrUnknownObjectKind = errors.New("unknown object kind")
+
+	// ErrUnsorted is returned by ParseStrict when the references of a document are not in the order of String.
+	ErrUnsorted = errors.New("references not sorted")
+
+	// ErrDuplicateIdentity is returned by ParseStrict when a document lists an identity twice with the same bom.
+	ErrDuplicateIdentity = errors.New("duplicate identity")
+
+	// ErrInvalidAnnotation is returned when an annotation key or value cannot be rendered in a document.
+	ErrInvalidAnnotation = errors.New("invalid annotation")
+
+	// ErrInvalidConfig is returned by NewFromConfig when a Config field holds an invalid value.
+	ErrInvalidConfig = errors.New("invalid config")
+
+	// ErrTooManyReferences is returned by ParseWithOptions when a document holds more references than permitted.
+	ErrTooManyReferences = errors.New("too many references")
+
+	// ErrObjectNotFound is returned when an ObjectStore holds no object for the requested identity.
+	ErrObjectNotFound = errors.New("object not found")
+
+	// ErrReferenceNotFound is returned when a reference cannot be reached from the given artifact tree.
+	ErrReferenceNotFound = errors.New("reference not found")
+
+	// ErrCycle is returned when the bom links of OmniBOR documents form a cycle, see Graph.
+	ErrCycle = errors.New("dependency cycle")
+
+	// ErrIdentityMismatch is returned when the content of a stored object does not hash to the identity it is stored under.
+	ErrIdentityMismatch = errors.New("object content does not match its identity")
+)
+
+// ContentLengthError is returned when content does not have the length stated for it, typically because
+// a file changed between being stat'ed and read. It matches ErrContentLengthMismatch and, depending on the direction,
+// ErrShortRead or ErrLongRead.
+type ContentLengthError struct {
+	// Path is the file the content was read from, empty if unknown.
+	Path string
+
+	// Expected is the stated content length.
+	Expected int64
+
+	// Actual is the number of bytes the reader held.
+	Actual int64
+}
+
+func (e *ContentLengthError) Error() string {
+	msg := fmt.Sprintf("%v: expected %d bytes, read %d", e.Unwrap(), e.Expected, e.Actual)
+	if e.Path != "" {
+		msg = e.Path + ": " + msg
+	}
+	return msg
+}
+
+// Unwrap returns ErrShortRead or ErrLongRead.
+func (e *ContentLengthError) Unwrap() error {
+	if e.Actual < e.Expected {
+		return ErrShortRead
+	}
+	return ErrLongRead
+}
+
+// Is reports whether target is ErrContentLengthMismatch.
+func (e *ContentLengthError) Is(target error) bool {
+	return target == ErrContentLengthMismatch
+}
+
+// FieldError is returned by ParseJSON when a field of the document is invalid.
+type FieldError struct {
+	// Field is the path of the field within the document, such as "references[2].bom".
+	Field string
+
+	// Err describes what is wrong with the field.
+	Err error
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// FileErrors is returned when files were skipped because of WithContinueOnError, holding an error for every
+// file or directory that could not be read. errors.Is and errors.As match any of the errors.
+type FileErrors []error
+
+func (e FileErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("%d files skipped: %s", len(e), strings.Join(msgs, "; "))
+}
+
+func (e FileErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e FileErrors) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// withPath sets the path of a ContentLengthError wrapped in err, if any, and returns err.
+func withPath(err error, path string) error {
+	var lengthErr *ContentLengthError
+	if errors.As(err, &lengthErr) {
+		lengthErr.Path = path
+	}
+	return err
+}
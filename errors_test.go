@@ -0,0 +1,149 @@
+package omnibor
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrInvalidHashLength(t *testing.T) {
+	err := NewSha1OmniBOR().AddExistingReference("04fea06420ca60892f73becee3614f6d023a4b7")
+	assert.True(t, errors.Is(err, ErrInvalidHashLength))
+
+	_, err = Parse(strings.NewReader("blob 04fea0\n"))
+	assert.True(t, errors.Is(err, ErrInvalidHashLength))
+
+	_, err = NewFileObjectStore(t.TempDir()).Get("04fea0")
+	assert.True(t, errors.Is(err, ErrInvalidHashLength))
+}
+
+func TestInvalidBom(t *testing.T) {
+	short, err := NewIdentifier("dc0be356")
+	assert.NoError(t, err)
+
+	gb := NewSha1OmniBOR()
+	err = gb.AddReference([]byte("hello"), short)
+	assert.True(t, errors.Is(err, ErrInvalidHashLength))
+	err = gb.AddReferenceFromReader(strings.NewReader("hello"), short, 5)
+	assert.True(t, errors.Is(err, ErrInvalidHashLength))
+
+	_, err = NewIdentifier("DC0BE356E8C2BA26E66448D97DB76AD050206574")
+	assert.True(t, errors.Is(err, ErrInvalidHex))
+
+	_, err = Parse(strings.NewReader("blob 04fea06420ca60892f73becee3614f6d023a4b7f bom dc0be356\n"))
+	assert.True(t, errors.Is(err, ErrInvalidHashLength))
+	assert.Equal(t, 0, gb.Len())
+
+	// a bom may use another hash algorithm than the tree
+	sha256 := NewSha256OmniBOR()
+	assert.NoError(t, sha256.AddReference([]byte("hello"), nil))
+	assert.NoError(t, gb.AddReference([]byte("hello"), sha256))
+}
+
+func TestErrInvalidHex(t *testing.T) {
+	err := NewSha1OmniBOR().AddExistingReference("04fea06420ca60892f73becee3614f6d023a4b7g")
+	assert.True(t, errors.Is(err, ErrInvalidHex))
+
+	_, err = NewIdentifier("not hex")
+	assert.True(t, errors.Is(err, ErrInvalidHex))
+
+	// every entry point rejects uppercase, which a document never holds
+	err = NewSha1OmniBOR().AddExistingReference("04FEA06420CA60892F73BECEE3614F6D023A4B7F")
+	assert.True(t, errors.Is(err, ErrInvalidHex))
+	_, err = Parse(strings.NewReader("blob 04FEA06420CA60892F73BECEE3614F6D023A4B7F\n"))
+	assert.True(t, errors.Is(err, ErrInvalidHex))
+	_, err = NewFileObjectStore(t.TempDir()).Get("04FEA06420CA60892F73BECEE3614F6D023A4B7F")
+	assert.True(t, errors.Is(err, ErrInvalidHex))
+}
+
+func TestErrAlgorithmMismatch(t *testing.T) {
+	err := NewSha1OmniBOR().AddExistingReference("8aec4e4876f854f688d0ebfc8f37598f38e5fd6903cccc850ca36591175aeb60")
+	assert.True(t, errors.Is(err, ErrAlgorithmMismatch))
+
+	err = NewSha256OmniBOR().AddExistingReference("04fea06420ca60892f73becee3614f6d023a4b7f")
+	assert.True(t, errors.Is(err, ErrAlgorithmMismatch))
+
+	_, err = Parse(strings.NewReader("blob 04fea06420ca60892f73becee3614f6d023a4b7f\n" +
+		"blob 8aec4e4876f854f688d0ebfc8f37598f38e5fd6903cccc850ca36591175aeb60\n"))
+	assert.True(t, errors.Is(err, ErrAlgorithmMismatch))
+}
+
+func TestErrInvalidRange(t *testing.T) {
+	err := NewSha1OmniBOR().AddReferenceRange(strings.NewReader("hello"), 0, -1, nil)
+	assert.True(t, errors.Is(err, ErrInvalidRange))
+}
+
+func TestErrMalformedReference(t *testing.T) {
+	_, err := Parse(strings.NewReader("dir 04fea06420ca60892f73becee3614f6d023a4b7f\n"))
+	assert.True(t, errors.Is(err, ErrMalformedReference))
+}
+
+func TestErrShortRead(t *testing.T) {
+	gb := NewSha1OmniBOR()
+	err := gb.AddReferenceFromReader(strings.NewReader("hello"), nil, 8)
+	assert.True(t, errors.Is(err, ErrShortRead))
+	assert.Equal(t, "short read: expected 8 bytes, read 5", err.Error())
+
+	err = gb.AddReferenceRange(strings.NewReader("hello"), 2, 5, nil)
+	assert.True(t, errors.Is(err, ErrShortRead))
+	assert.Equal(t, "short read: expected 5 bytes, read 3", err.Error())
+	assert.Equal(t, 0, gb.Len())
+}
+
+func TestErrLongRead(t *testing.T) {
+	gb := NewSha256OmniBOR()
+	err := gb.AddReferenceFromReader(strings.NewReader("hello world"), nil, 5)
+	assert.True(t, errors.Is(err, ErrLongRead))
+	assert.Equal(t, "long read: expected 5 bytes, read 11", err.Error())
+
+	err = gb.AddReferenceFromReader(strings.NewReader("hello"), nil, 0)
+	assert.True(t, errors.Is(err, ErrLongRead))
+	assert.Equal(t, "long read: expected 0 bytes, read 5", err.Error())
+	assert.Equal(t, 0, gb.Len())
+
+	assert.NoError(t, gb.AddReferenceFromReader(strings.NewReader(""), nil, 0))
+	assert.Equal(t, "473a0f4c3be8a93681a267e3b1e9a7dcda1185436fe141f7749120a303721813", gb.References()[0].Identity())
+}
+
+func TestErrContentLengthMismatch(t *testing.T) {
+	gb := NewSha1OmniBOR()
+	err := gb.AddReferenceFromReader(iotest.HalfReader(strings.NewReader("hello")), nil, 6)
+	assert.True(t, errors.Is(err, ErrContentLengthMismatch))
+	assert.True(t, errors.Is(err, ErrShortRead))
+	assert.False(t, errors.Is(err, ErrLongRead))
+
+	var lengthErr *ContentLengthError
+	require.True(t, errors.As(err, &lengthErr))
+	assert.Equal(t, &ContentLengthError{Expected: 6, Actual: 5}, lengthErr)
+
+	err = withPath(err, "dir/hello")
+	assert.Equal(t, "dir/hello: short read: expected 6 bytes, read 5", err.Error())
+
+	err = gb.AddReferenceFromReader(strings.NewReader("hello"), nil, 4)
+	assert.True(t, errors.Is(err, ErrContentLengthMismatch))
+	assert.True(t, errors.Is(err, ErrLongRead))
+
+	assert.Nil(t, withPath(nil, "dir/hello"))
+}
+
+func TestAddReferenceChecked(t *testing.T) {
+	gb := NewSha1OmniBOR()
+	assert.NoError(t, gb.AddReferenceChecked([]byte("hello"), 5, nil))
+	assert.Equal(t, "blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\n", gb.String())
+
+	err := gb.AddReferenceChecked([]byte("world"), 6, nil)
+	assert.True(t, errors.Is(err, ErrShortRead))
+	assert.Equal(t, "short read: expected 6 bytes, read 5", err.Error())
+
+	err = gb.AddReferenceChecked([]byte("world"), 4, nil)
+	assert.True(t, errors.Is(err, ErrLongRead))
+	assert.True(t, errors.Is(err, ErrContentLengthMismatch))
+
+	err = gb.AddReferenceChecked([]byte("world"), -1, nil)
+	assert.True(t, errors.Is(err, ErrInvalidRange))
+	assert.Equal(t, 1, gb.Len())
+}
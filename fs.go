@@ -0,0 +1,68 @@
+package omnibor
+
+import (
+	"context"
+	"io/fs"
+)
+
+// AddFS walks root within fsys and adds a reference for every file below it, see AddTree.
+// Files are opened through fsys, so symbolic links are resolved the way fsys resolves them and WithFollowSymlinks
+// has no effect; fs.WalkDir does not descend into directories reached through a link and such links are skipped.
+func (srv *omniBor) AddFS(fsys fs.FS, root string, opts ...Option) error {
+	o := srv.ingestOptions(opts)
+	walk := func(fn func(fileEvent) error) error {
+		return fs.WalkDir(fsys, root, func(name string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if !o.skip(err) {
+					return err
+				}
+				if d != nil && d.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if excluded, err := o.excluded(root, name); err != nil || excluded {
+				if err == nil && d.IsDir() {
+					return fs.SkipDir
+				}
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			// FIFOs, sockets and devices are skipped, also behind links, as opening them may block
+			if d.Type()&fs.ModeSymlink != 0 {
+				if info, err := fs.Stat(fsys, name); err == nil && !info.Mode().IsRegular() && !info.IsDir() {
+					return nil
+				}
+			} else if !d.Type().IsRegular() {
+				return nil
+			}
+			return fn(fileEvent{path: name, name: name})
+		})
+	}
+	add := func(ctx context.Context, ev fileEvent) (string, int64, error) {
+		return srv.addFSFile(o, fsys, ev.path)
+	}
+	return srv.addFiles(context.Background(), o, walk, add)
+}
+
+// addFSFile adds a reference for the file name of fsys, taking its length from the opened file.
+// name is skipped if it turns out to be a link to a directory. It returns the gitoid and size of the file.
+func (srv *omniBor) addFSFile(o *options, fsys fs.FS, name string) (string, int64, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", 0, err
+	}
+	if info.IsDir() {
+		return "", 0, nil
+	}
+	identity, err := srv.addFileID(o, f, info.Size())
+	return identity, info.Size(), withPath(err, name)
+}
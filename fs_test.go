@@ -0,0 +1,52 @@
+package omnibor
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"src/a/hello":       {Data: []byte("hello")},
+		"src/b/world":       {Data: []byte("world")},
+		"src/vendor/hello2": {Data: []byte("hello2")},
+		"other/independent": {Data: []byte("independent")},
+	}
+
+	gb := NewSha1OmniBOR()
+	require.NoError(t, gb.AddFS(fsys, "src", WithExclude("vendor"), WithWorkers(2)))
+	assert.Equal(t, "dc0be356e8c2ba26e66448d97db76ad050206574", gb.Identity())
+
+	gb = NewSha256OmniBOR()
+	require.NoError(t, gb.AddFS(fsys, "src", WithExclude("vendor")))
+	assert.Equal(t, "e32e7e7761709be17ef573556a82960d489ddf0092424f7db1c91d8363dde822", gb.Identity())
+
+	gb = NewSha1OmniBOR()
+	require.NoError(t, gb.AddFS(fsys, "."))
+	assert.Equal(t, 4, gb.Len())
+
+	err := NewSha1OmniBOR().AddFS(fsys, "missing")
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+}
+
+func TestAddFSDirFS(t *testing.T) {
+	root := createTree(t)
+	external := t.TempDir()
+	writeTestFile(t, external, "independent", "independent")
+	require.NoError(t, os.Symlink(filepath.Join(external, "independent"), filepath.Join(root, "link")))
+
+	gb := NewSha1OmniBOR()
+	require.NoError(t, gb.AddFS(os.DirFS(root), "."))
+
+	// linked files are read through the link, the linked directory c holding hello2 is skipped
+	assert.Equal(t, "blob 04fea06420ca60892f73becee3614f6d023a4b7f\n"+
+		"blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\n"+
+		"blob be78cc5602c5457f144a67e574b8f98b9dc2a1a0\n", gb.String())
+}
@@ -0,0 +1,155 @@
+package omnibor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Graph is the dependency DAG of one or more OmniBOR documents. Its nodes are identities of documents and of the
+// artifacts they list, an edge leads from a node to a node it depends on: from a document to every artifact it lists,
+// and from an artifact to the document of its bom link, the tree it was built from.
+type Graph struct {
+	// Nodes holds every identity of the graph in ascending order.
+	Nodes []string
+
+	// Edges holds every edge of the graph, ordered by From then To.
+	Edges []Edge
+}
+
+// Edge leads from an identity to an identity it depends on.
+type Edge struct {
+	From string
+	To   string
+}
+
+// graphBuilder collects the nodes and edges of a Graph, ignoring repeated ones.
+type graphBuilder struct {
+	nodes map[string]bool
+	edges map[Edge]bool
+}
+
+func newGraphBuilder() *graphBuilder {
+	return &graphBuilder{
+		nodes: make(map[string]bool),
+		edges: make(map[Edge]bool),
+	}
+}
+
+// addDocument adds the edges of the document with the given identity listing refs.
+func (b *graphBuilder) addDocument(identity string, refs []Reference) {
+	b.nodes[identity] = true
+	for _, ref := range refs {
+		b.nodes[ref.Identity()] = true
+		b.edges[Edge{From: identity, To: ref.Identity()}] = true
+		if ref.Bom() != nil {
+			b.nodes[ref.Bom().Identity()] = true
+			b.edges[Edge{From: ref.Identity(), To: ref.Bom().Identity()}] = true
+		}
+	}
+}
+
+// graph returns the collected graph, or an error wrapping ErrCycle naming the identities of a cycle.
+func (b *graphBuilder) graph() (*Graph, error) {
+	g := &Graph{
+		Nodes: make([]string, 0, len(b.nodes)),
+		Edges: make([]Edge, 0, len(b.edges)),
+	}
+	for node := range b.nodes {
+		g.Nodes = append(g.Nodes, node)
+	}
+	sort.Strings(g.Nodes)
+	for edge := range b.edges {
+		g.Edges = append(g.Edges, edge)
+	}
+	sort.Slice(g.Edges, func(i, j int) bool {
+		if g.Edges[i].From != g.Edges[j].From {
+			return g.Edges[i].From < g.Edges[j].From
+		}
+		return g.Edges[i].To < g.Edges[j].To
+	})
+
+	if cycle := g.cycle(); cycle != nil {
+		return nil, fmt.Errorf("%w: %s", ErrCycle, strings.Join(cycle, " -> "))
+	}
+	return g, nil
+}
+
+// cycle returns the identities along a cycle of g, the first one repeated at the end, or nil if g is acyclic.
+func (g *Graph) cycle() []string {
+	successors := make(map[string][]string, len(g.Nodes))
+	for _, edge := range g.Edges {
+		successors[edge.From] = append(successors[edge.From], edge.To)
+	}
+
+	const (
+		unvisited = iota
+		onPath
+		done
+	)
+	state := make(map[string]int, len(g.Nodes))
+	var path []string
+	var visit func(node string) []string
+	visit = func(node string) []string {
+		state[node] = onPath
+		path = append(path, node)
+		for _, next := range successors[node] {
+			switch state[next] {
+			case onPath:
+				for i, n := range path {
+					if n == next {
+						return append(append([]string(nil), path[i:]...), next)
+					}
+				}
+			case unvisited:
+				if cycle := visit(next); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[node] = done
+		return nil
+	}
+	for _, node := range g.Nodes {
+		if state[node] == unvisited {
+			if cycle := visit(node); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+func (srv *omniBor) Graph() (*Graph, error) {
+	b := newGraphBuilder()
+	b.addDocument(srv.Identity(), srv.References())
+	return b.graph()
+}
+
+// BuildGraph returns the dependency graph of the document stored under root and of every document reached from it
+// through bom links, see Graph. Bom links whose documents are not in store end at the bom identity.
+// An error wrapping ErrCycle is returned if the graph has a cycle, which well-formed documents cannot produce.
+func BuildGraph(store ObjectStore, root string) (*Graph, error) {
+	b := newGraphBuilder()
+	queue := []string{root}
+	for seen := map[string]bool{root: true}; len(queue) > 0; queue = queue[1:] {
+		tree, err := loadTree(store, queue[0])
+		if err != nil {
+			return nil, err
+		}
+		refs := tree.References()
+		b.addDocument(queue[0], refs)
+		for _, ref := range refs {
+			if ref.Bom() == nil {
+				continue
+			}
+			bom := ref.Bom().Identity()
+			if !seen[bom] && store.Has(bom) {
+				seen[bom] = true
+				queue = append(queue, bom)
+			}
+		}
+	}
+	return b.graph()
+}
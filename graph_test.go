@@ -0,0 +1,61 @@
+package omnibor
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildGraph(t *testing.T) {
+	store := NewMemoryStore()
+	top, leaf := storeTwoLevelTree(t, store)
+
+	const (
+		hello       = "b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0"
+		world       = "04fea06420ca60892f73becee3614f6d023a4b7f"
+		hello2      = "23294b0610492cf55c1c4835216f20d376a287dd"
+		independent = "be78cc5602c5457f144a67e574b8f98b9dc2a1a0"
+	)
+	graph, err := BuildGraph(store, top.Identity())
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []Edge{
+		{From: top.Identity(), To: hello2},
+		{From: top.Identity(), To: independent},
+		{From: hello2, To: leaf.Identity()},
+		{From: leaf.Identity(), To: hello},
+		{From: leaf.Identity(), To: world},
+	}, graph.Edges)
+	assert.ElementsMatch(t, []string{top.Identity(), leaf.Identity(), hello, world, hello2, independent}, graph.Nodes)
+	assert.IsIncreasing(t, graph.Nodes)
+
+	// a single document ends at its bom links
+	graph, err = top.Graph()
+	require.NoError(t, err)
+	assert.Equal(t, []Edge{
+		{From: hello2, To: leaf.Identity()},
+		{From: top.Identity(), To: hello2},
+		{From: top.Identity(), To: independent},
+	}, graph.Edges)
+
+	_, err = BuildGraph(store, "0000000000000000000000000000000000000000")
+	assert.True(t, errors.Is(err, ErrObjectNotFound))
+}
+
+func TestGraphCycle(t *testing.T) {
+	const (
+		a = "04fea06420ca60892f73becee3614f6d023a4b7f"
+		b = "b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0"
+	)
+	refA, err := NewReference(a, &identifier{identity: b})
+	require.NoError(t, err)
+	refB, err := NewReference(b, &identifier{identity: a})
+	require.NoError(t, err)
+
+	gb := NewSha1OmniBOR()
+	require.NoError(t, gb.AddReferences([]Reference{refA, refB}))
+	_, err = gb.Graph()
+	assert.True(t, errors.Is(err, ErrCycle))
+	assert.Contains(t, err.Error(), a+" -> "+b+" -> "+a)
+}
@@ -0,0 +1,133 @@
+package omnibor
+
+import (
+	"crypto/sha1" // #nosec G505 -- sha1 gitoids are part of the spec
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"strconv"
+	"sync"
+)
+
+// copyBufferSize is the default size of the buffers content is copied into the hasher with, see WithBufferSize.
+const copyBufferSize = 32 * 1024
+
+// hasherPools hold reset hashers per algorithm so that hashing a reference does not allocate a new one.
+var hasherPools = map[HashAlgorithm]*sync.Pool{
+	Sha1: {
+		New: func() interface{} {
+			return sha1.New() // #nosec G401 -- sha1 gitoids are part of the spec
+		},
+	},
+	Sha256: {
+		New: func() interface{} {
+			return sha256.New()
+		},
+	},
+}
+
+var copyBuffers = newBufferPool(copyBufferSize)
+
+// newBufferPool returns a pool of copy buffers of size bytes.
+func newBufferPool(size int) *sync.Pool {
+	return &sync.Pool{
+		New: func() interface{} {
+			buf := make([]byte, size)
+			return &buf
+		},
+	}
+}
+
+// GitOID returns the gitoid of the blob made of the length bytes of reader, computed with algo, as a hex string.
+// It is the identity a tree would record for that content, without building a tree.
+// An error wrapping ErrShortRead or ErrLongRead is returned if reader does not hold exactly length bytes.
+func GitOID(reader io.Reader, length int64, algo HashAlgorithm) (string, error) {
+	gb, err := newTreeForAlgorithm(algo)
+	if err != nil {
+		return "", err
+	}
+	return gb.hash(reader, length)
+}
+
+// hash computes the gitoid of length bytes read from reader using the tree's hash algorithm.
+// The result is the same as gitoid.New with gitoid.WithContentLength, hashers and copy buffers are pooled.
+func (srv *omniBor) hash(reader io.Reader, length int64) (string, error) {
+	return srv.hashObject(KindBlob, reader, length, copyBuffers)
+}
+
+// hashObject is hash for an object of the given kind, which is hashed into the git object header,
+// copying the content with buffers from buffers.
+func (srv *omniBor) hashObject(kind ObjectKind, reader io.Reader, length int64, buffers *sync.Pool) (string, error) {
+	pool := srv.hashers
+	if pool == nil {
+		pool = hasherPools[srv.hashType]
+	}
+	h := pool.Get().(hash.Hash)
+	defer release(pool, h)
+
+	if err := writeGitObject(h, kind, reader, length, buffers); err != nil {
+		return "", err
+	}
+	return hexSum(h), nil
+}
+
+// hashDual computes both the sha1 and the sha256 gitoid of length bytes read from reader in a single pass.
+func hashDual(reader io.Reader, length int64) (string, string, error) {
+	h1 := hasherPools[Sha1].Get().(hash.Hash)
+	defer release(hasherPools[Sha1], h1)
+	h256 := hasherPools[Sha256].Get().(hash.Hash)
+	defer release(hasherPools[Sha256], h256)
+
+	if err := writeGitObject(io.MultiWriter(h1, h256), KindBlob, reader, length, copyBuffers); err != nil {
+		return "", "", err
+	}
+	return hexSum(h1), hexSum(h256), nil
+}
+
+func release(pool *sync.Pool, h hash.Hash) {
+	h.Reset()
+	pool.Put(h)
+}
+
+func hexSum(h hash.Hash) string {
+	var sum [sha256.Size]byte
+	return hex.EncodeToString(h.Sum(sum[:0]))
+}
+
+// writeGitObject writes the git object header of kind followed by exactly length bytes of reader to w,
+// copying them with a buffer taken from buffers.
+// It fails with a ContentLengthError if reader holds fewer or more bytes. A length of 0 is the empty blob,
+// a negative length is rejected with ErrInvalidRange before anything is written.
+func writeGitObject(w io.Writer, kind ObjectKind, reader io.Reader, length int64, buffers *sync.Pool) error {
+	if length < 0 {
+		return fmt.Errorf("%w: negative content length %d", ErrInvalidRange, length)
+	}
+
+	buf := buffers.Get().(*[]byte)
+	defer buffers.Put(buf)
+
+	// the git object header "<kind> <length>\x00", as produced by gitoid.Header
+	var header [32]byte
+	if _, err := w.Write(append(strconv.AppendInt(append(append(header[:0], kind...), ' '), length, 10), 0)); err != nil {
+		return err
+	}
+	n, err := io.CopyBuffer(w, io.LimitReader(reader, length), *buf)
+	if err != nil {
+		return err
+	}
+	if n < length {
+		return &ContentLengthError{Expected: length, Actual: n}
+	}
+
+	// anything left over means the stated length was too short
+	extra, err := io.CopyBuffer(io.Discard, reader, *buf)
+	if err != nil {
+		return err
+	}
+	if extra > 0 {
+		return &ContentLengthError{Expected: length, Actual: length + extra}
+	}
+	return nil
+}
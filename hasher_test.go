@@ -0,0 +1,162 @@
+package omnibor
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"hash"
+	"math/rand"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/edwarnicke/gitoid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashMatchesGitoid(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, size := range []int{0, 1, 5, copyBufferSize - 1, copyBufferSize, copyBufferSize + 1, 3*copyBufferSize + 17} {
+		content := make([]byte, size)
+		_, _ = r.Read(content)
+
+		for _, gb := range []*omniBor{NewSha1OmniBOR().(*omniBor), NewSha256OmniBOR().(*omniBor)} {
+			opts := []gitoid.Option{gitoid.WithContentLength(int64(size))}
+			if gb.hashType == Sha256 {
+				opts = append(opts, gitoid.WithSha256())
+			}
+			expected, err := gitoid.New(bytes.NewReader(content), opts...)
+			require.NoError(t, err)
+
+			// hash twice so the second run uses a pooled hasher
+			for i := 0; i < 2; i++ {
+				actual, err := gb.hash(bytes.NewReader(content), int64(size))
+				assert.NoError(t, err)
+				assert.Equal(t, expected.String(), actual, "size %d", size)
+			}
+		}
+	}
+}
+
+func TestGitOID(t *testing.T) {
+	expected := map[HashAlgorithm]map[string]string{
+		Sha1: {
+			"hello": "b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0",
+			"world": "04fea06420ca60892f73becee3614f6d023a4b7f",
+			"":      "e69de29bb2d1d6434b8b29ae775ad8c2e48c5391",
+		},
+		Sha256: {
+			"hello": "8aec4e4876f854f688d0ebfc8f37598f38e5fd6903cccc850ca36591175aeb60",
+			"world": "8df3dab4ddfa6eb2a34065cda27d95af2709d4d2658e1b5fbd145822acf42b28",
+			"":      "473a0f4c3be8a93681a267e3b1e9a7dcda1185436fe141f7749120a303721813",
+		},
+	}
+	for algo, gitoids := range expected {
+		for content, gitoid := range gitoids {
+			actual, err := GitOID(strings.NewReader(content), int64(len(content)), algo)
+			assert.NoError(t, err)
+			assert.Equal(t, gitoid, actual, "%s %q", algo, content)
+		}
+	}
+
+	_, err := GitOID(strings.NewReader("hello"), 4, Sha1)
+	assert.True(t, errors.Is(err, ErrLongRead))
+
+	_, err = GitOID(strings.NewReader("hello"), 5, "md5")
+	assert.True(t, errors.Is(err, ErrUnknownAlgorithm))
+}
+
+func BenchmarkHash(b *testing.B) {
+	gb := NewSha256OmniBOR().(*omniBor)
+	content := make([]byte, 64)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := gb.hash(bytes.NewReader(content), int64(len(content))); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkHashGitoid(b *testing.B) {
+	content := make([]byte, 64)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := gitoid.New(bytes.NewReader(content), gitoid.WithContentLength(int64(len(content))), gitoid.WithSha256()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestHashEmptyAndNegativeLength(t *testing.T) {
+	gb := NewSha1OmniBOR()
+	assert.NoError(t, gb.AddReferenceFromReader(strings.NewReader(""), nil, 0))
+	assert.Equal(t, "blob e69de29bb2d1d6434b8b29ae775ad8c2e48c5391\n", gb.String())
+
+	err := gb.AddReferenceFromReader(strings.NewReader("hello"), nil, -1)
+	assert.True(t, errors.Is(err, ErrInvalidRange))
+	assert.False(t, errors.Is(err, ErrContentLengthMismatch))
+	assert.Equal(t, "invalid range: negative content length -1", err.Error())
+
+	_, err = GitOID(strings.NewReader(""), -5, Sha256)
+	assert.True(t, errors.Is(err, ErrInvalidRange))
+
+	_, _, err = hashDual(strings.NewReader(""), -1)
+	assert.True(t, errors.Is(err, ErrInvalidRange))
+	assert.Equal(t, 1, gb.Len())
+}
+
+// countingHash is a sha256 hash counting the bytes written to every instance.
+type countingHash struct {
+	hash.Hash
+	written *int64
+}
+
+func (h countingHash) Write(p []byte) (int, error) {
+	atomic.AddInt64(h.written, int64(len(p)))
+	return h.Hash.Write(p)
+}
+
+func TestWithHashFunc(t *testing.T) {
+	var written int64
+	gb := NewSha256OmniBOR(WithHashFunc(func() hash.Hash {
+		return countingHash{Hash: sha256.New(), written: &written}
+	}))
+	require.NoError(t, gb.AddReference([]byte("hello"), nil))
+	require.NoError(t, gb.AddReference([]byte("world"), nil))
+	assert.Equal(t, "e32e7e7761709be17ef573556a82960d489ddf0092424f7db1c91d8363dde822", gb.Identity())
+	// two objects of "blob 5\x00" and 5 bytes, then "blob 140\x00" and the document of two 70 byte lines
+	const expected = 2*(7+5) + 9 + 2*70
+	assert.Equal(t, int64(expected), atomic.LoadInt64(&written))
+
+	clone := gb.Clone()
+	require.NoError(t, clone.AddReference([]byte("hello2"), nil))
+	assert.Greater(t, atomic.LoadInt64(&written), int64(expected))
+
+	// another hash function yields other identities
+	salted := NewSha256OmniBOR(WithHashFunc(func() hash.Hash {
+		h := sha256.New()
+		h.Write([]byte("salt"))
+		return &saltedHash{Hash: h}
+	}))
+	require.NoError(t, salted.AddReference([]byte("hello"), nil))
+	assert.NotEqual(t, "8aec4e4876f854f688d0ebfc8f37598f38e5fd6903cccc850ca36591175aeb60", salted.References()[0].Identity())
+
+	assert.Panics(t, func() {
+		NewSha1OmniBOR(WithHashFunc(sha256.New))
+	})
+}
+
+// saltedHash is a sha256 hash of its content prefixed with "salt", kept across Reset.
+type saltedHash struct {
+	hash.Hash
+}
+
+func (h *saltedHash) Reset() {
+	h.Hash.Reset()
+	h.Hash.Write([]byte("salt"))
+}
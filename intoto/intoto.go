@@ -0,0 +1,64 @@
+/*
+Package intoto wraps OmniBOR artifact trees in in-toto v1 Statements so they can be signed,
+for example with cosign, and verified alongside other supply chain attestations.
+
+The statement subject is the artifact the tree describes. Its digest is the tree identity, which is the git
+blob object id of the OmniBOR document and is therefore recorded under the in-toto "gitBlob" digest algorithm.
+The predicate is the canonical JSON form of the tree, see omnibor.ArtifactTree.CanonicalJSON.
+*/
+package intoto
+
+import (
+	"encoding/json"
+	"errors"
+
+	omnibor "github.com/omnibor/omnibor-go"
+)
+
+const (
+	// StatementType is the in-toto Statement layer version emitted by ToInTotoStatement.
+	StatementType = "https://in-toto.io/Statement/v1"
+
+	// PredicateType identifies OmniBOR artifact tree predicates.
+	PredicateType = "https://omnibor.io/artifact-tree/v0.1"
+
+	// DigestAlgorithm is the in-toto digest set key holding the tree identity.
+	DigestAlgorithm = "gitBlob"
+)
+
+// ErrEmptySubject is returned when the statement subject has no name.
+var ErrEmptySubject = errors.New("empty subject name")
+
+type statement struct {
+	Type          string               `json:"_type"`
+	Subject       []resourceDescriptor `json:"subject"`
+	PredicateType string               `json:"predicateType"`
+	Predicate     json.RawMessage      `json:"predicate"`
+}
+
+type resourceDescriptor struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// ToInTotoStatement renders an in-toto Statement naming subject and embedding tree as its predicate.
+// The result is unsigned JSON ready to be handed to a signer.
+func ToInTotoStatement(subject string, tree omnibor.ArtifactTree) ([]byte, error) {
+	if subject == "" {
+		return nil, ErrEmptySubject
+	}
+
+	return json.Marshal(statement{
+		Type: StatementType,
+		Subject: []resourceDescriptor{
+			{
+				Name: subject,
+				Digest: map[string]string{
+					DigestAlgorithm: tree.Identity(),
+				},
+			},
+		},
+		PredicateType: PredicateType,
+		Predicate:     tree.CanonicalJSON(),
+	})
+}
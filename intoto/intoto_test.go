@@ -0,0 +1,70 @@
+package intoto
+
+import (
+	"encoding/json"
+	"testing"
+
+	omnibor "github.com/omnibor/omnibor-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToInTotoStatement(t *testing.T) {
+	leaf := omnibor.NewSha1OmniBOR()
+	require.NoError(t, leaf.AddReference([]byte("hello"), nil))
+
+	tree := omnibor.NewSha1OmniBOR()
+	require.NoError(t, tree.AddReference([]byte("hello2"), leaf))
+	require.NoError(t, tree.AddReference([]byte("world"), nil))
+
+	out, err := ToInTotoStatement("a.out", tree)
+	require.NoError(t, err)
+
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(out, &doc))
+	assert.Equal(t, StatementType, doc["_type"])
+	assert.Equal(t, PredicateType, doc["predicateType"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{
+			"name": "a.out",
+			"digest": map[string]interface{}{
+				"gitBlob": tree.Identity(),
+			},
+		},
+	}, doc["subject"])
+
+	var st statement
+	require.NoError(t, json.Unmarshal(out, &st))
+	assert.Equal(t, tree.Identity(), st.Subject[0].Digest[DigestAlgorithm])
+	assert.JSONEq(t, string(tree.CanonicalJSON()), string(st.Predicate))
+
+	predicate := doc["predicate"].(map[string]interface{})
+	assert.Equal(t, tree.Identity(), predicate["identity"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{
+			"identity": "04fea06420ca60892f73becee3614f6d023a4b7f",
+		},
+		map[string]interface{}{
+			"bom":      leaf.Identity(),
+			"identity": "23294b0610492cf55c1c4835216f20d376a287dd",
+		},
+	}, predicate["references"])
+}
+
+func TestToInTotoStatementSha256(t *testing.T) {
+	tree := omnibor.NewSha256OmniBOR()
+	require.NoError(t, tree.AddReference([]byte("hello"), nil))
+
+	out, err := ToInTotoStatement("hello", tree)
+	require.NoError(t, err)
+
+	var st statement
+	require.NoError(t, json.Unmarshal(out, &st))
+	assert.Len(t, st.Subject[0].Digest[DigestAlgorithm], 64)
+	assert.Equal(t, tree.Identity(), st.Subject[0].Digest[DigestAlgorithm])
+}
+
+func TestToInTotoStatementEmptySubject(t *testing.T) {
+	_, err := ToInTotoStatement("", omnibor.NewSha1OmniBOR())
+	assert.ErrorIs(t, err, ErrEmptySubject)
+}
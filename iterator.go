@@ -0,0 +1,39 @@
+package omnibor
+
+// ReferenceIterator pages through the references of an ArtifactTree, see ArtifactTree.Iterator.
+type ReferenceIterator interface {
+	// Next returns the next page of references and true, or nil and false once every reference was returned.
+	// The last page may be shorter than the page size.
+	Next() ([]Reference, bool)
+}
+
+type referenceIterator struct {
+	refs     []Reference // sorted snapshot taken by Iterator
+	pageSize int
+}
+
+func (srv *omniBor) Iterator(pageSize int) ReferenceIterator {
+	if pageSize < 1 {
+		pageSize = 1
+	}
+
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+	return &referenceIterator{
+		refs:     srv.sortedReferences(),
+		pageSize: pageSize,
+	}
+}
+
+func (it *referenceIterator) Next() ([]Reference, bool) {
+	if len(it.refs) == 0 {
+		return nil, false
+	}
+	end := it.pageSize
+	if end > len(it.refs) {
+		end = len(it.refs)
+	}
+	page := it.refs[:end:end]
+	it.refs = it.refs[end:]
+	return page, true
+}
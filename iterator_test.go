@@ -0,0 +1,58 @@
+package omnibor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIterator(t *testing.T) {
+	gb := NewSha1OmniBOR()
+	require.NoError(t, gb.AddExistingReferences(generateIdentities(1000)))
+
+	var all []Reference
+	pages := 0
+	it := gb.Iterator(100)
+	for {
+		page, ok := it.Next()
+		if !ok {
+			break
+		}
+		assert.Len(t, page, 100)
+		all = append(all, page...)
+		pages++
+	}
+	assert.Equal(t, 10, pages)
+	assert.Equal(t, gb.References(), all)
+
+	page, ok := it.Next()
+	assert.False(t, ok)
+	assert.Nil(t, page)
+}
+
+func TestIteratorShortPages(t *testing.T) {
+	gb := NewSha1OmniBOR()
+	assert.NoError(t, gb.AddReference([]byte("hello"), nil))
+	assert.NoError(t, gb.AddReference([]byte("world"), nil))
+	assert.NoError(t, gb.AddReference([]byte("hello2"), nil))
+
+	it := gb.Iterator(2)
+	page, ok := it.Next()
+	require.True(t, ok)
+	assert.Equal(t, gb.References()[:2], page)
+
+	// references added after Iterator are not seen
+	assert.NoError(t, gb.AddExistingReference("0000000000000000000000000000000000000000"))
+	assert.NoError(t, gb.AddReference([]byte("independent"), nil))
+	page, ok = it.Next()
+	require.True(t, ok)
+	require.Len(t, page, 1)
+	assert.Equal(t, "b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0", page[0].Identity())
+
+	_, ok = it.Next()
+	assert.False(t, ok)
+
+	_, ok = NewSha256OmniBOR().Iterator(0).Next()
+	assert.False(t, ok)
+}
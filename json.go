@@ -0,0 +1,218 @@
+package omnibor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// jsonDocument is the JSON form of an ArtifactTree.
+// Fields are declared in lexicographic key order so encoding/json emits sorted keys.
+type jsonDocument struct {
+	Algorithm  string          `json:"algorithm"`
+	Identity   string          `json:"identity"`
+	References []jsonReference `json:"references"`
+}
+
+type jsonReference struct {
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Bom         string            `json:"bom,omitempty"`
+	Identity    string            `json:"identity"`
+	Kind        ObjectKind        `json:"kind,omitempty"` // omitted for a blob
+}
+
+func newJSONReference(ref Reference) jsonReference {
+	r := jsonReference{
+		Annotations: ref.Annotations(),
+		Identity:    ref.Identity(),
+		Kind:        kindOf(ref.Kind()),
+	}
+	if ref.Bom() != nil {
+		r.Bom = ref.Bom().Identity()
+	}
+	return r
+}
+
+func (srv *omniBor) jsonDocument() jsonDocument {
+	srv.lock.Lock()
+	refs := srv.sortedReferences()
+	srv.lock.Unlock()
+
+	doc := jsonDocument{
+		Algorithm:  string(srv.hashType),
+		Identity:   srv.Identity(),
+		References: make([]jsonReference, 0, len(refs)),
+	}
+	for _, ref := range refs {
+		doc.References = append(doc.References, newJSONReference(ref))
+	}
+	return doc
+}
+
+// MarshalJSON implements json.Marshaler, it returns the CanonicalJSON encoding.
+func (srv *omniBor) MarshalJSON() ([]byte, error) {
+	return srv.CanonicalJSON(), nil
+}
+
+func (srv *omniBor) CanonicalJSON() []byte {
+	out, err := json.Marshal(srv.jsonDocument())
+	if err != nil {
+		// the document only holds strings, this can only fail if the runtime is broken
+		panic(err)
+	}
+	return out
+}
+
+func (srv *omniBor) WriteJSONL(w io.Writer) error {
+	srv.lock.Lock()
+	refs := srv.sortedReferences()
+	srv.lock.Unlock()
+
+	enc := json.NewEncoder(w)
+	for _, ref := range refs {
+		if err := enc.Encode(newJSONReference(ref)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseJSON reads a document in the form written by CanonicalJSON and reconstructs the tree, for services
+// accepting OmniBOR JSON from untrusted clients. The document is validated before anything is built:
+// fields that are not part of the format, missing algorithm, references or reference identities, identities
+// that are not lowercase gitoids of the document's algorithm, bom identities that are not lowercase gitoids and
+// annotations that cannot be rendered are each rejected with a *FieldError naming the offending field.
+// The identity field is optional, if given it must match the identity of the reconstructed tree.
+func ParseJSON(r io.Reader) (ArtifactTree, error) {
+	dec := json.NewDecoder(r)
+	var fields map[string]json.RawMessage
+	if err := dec.Decode(&fields); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("unexpected data after the JSON document")
+	}
+	if err := checkJSONFields("", fields, "algorithm", "identity", "references"); err != nil {
+		return nil, err
+	}
+
+	var algo string
+	if err := decodeJSONField("", "algorithm", fields, &algo); err != nil {
+		return nil, err
+	}
+	gb, err := newTreeForAlgorithm(HashAlgorithm(algo))
+	if err != nil {
+		return nil, &FieldError{Field: "algorithm", Err: err}
+	}
+
+	var refs []map[string]json.RawMessage
+	if err := decodeJSONField("", "references", fields, &refs); err != nil {
+		return nil, err
+	}
+	for i, ref := range refs {
+		if err := gb.addJSONReference(fmt.Sprintf("references[%d].", i), ref); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, ok := fields["identity"]; ok {
+		var identity string
+		if err := decodeJSONField("", "identity", fields, &identity); err != nil {
+			return nil, err
+		}
+		if identity != gb.Identity() {
+			return nil, &FieldError{
+				Field: "identity",
+				Err:   fmt.Errorf("%w: references hash to %s, not %s", ErrIdentityMismatch, gb.Identity(), identity),
+			}
+		}
+	}
+	return gb, nil
+}
+
+// addJSONReference validates the fields of a reference, whose field paths start with prefix, and adds it.
+func (srv *omniBor) addJSONReference(prefix string, fields map[string]json.RawMessage) error {
+	if err := checkJSONFields(prefix, fields, "annotations", "bom", "identity", "kind"); err != nil {
+		return err
+	}
+
+	kind := KindBlob
+	if _, ok := fields["kind"]; ok {
+		if err := decodeJSONField(prefix, "kind", fields, &kind); err != nil {
+			return err
+		}
+		if err := validateKind(kind); err != nil {
+			return &FieldError{Field: prefix + "kind", Err: err}
+		}
+	}
+
+	var identity string
+	if err := decodeJSONField(prefix, "identity", fields, &identity); err != nil {
+		return err
+	}
+	if err := srv.validateReference(identity, nil); err != nil {
+		return &FieldError{Field: prefix + "identity", Err: err}
+	}
+
+	var bom Identifier
+	if _, ok := fields["bom"]; ok {
+		var bomIdentity string
+		if err := decodeJSONField(prefix, "bom", fields, &bomIdentity); err != nil {
+			return err
+		}
+		bom = &identifier{identity: bomIdentity}
+		if err := validateBom(bom); err != nil {
+			return &FieldError{Field: prefix + "bom", Err: err}
+		}
+	}
+
+	var annotations map[string]string
+	if _, ok := fields["annotations"]; ok {
+		if err := decodeJSONField(prefix, "annotations", fields, &annotations); err != nil {
+			return err
+		}
+		if err := validateAnnotations(annotations); err != nil {
+			return &FieldError{Field: prefix + "annotations", Err: err}
+		}
+	}
+
+	return srv.addParsedReference(reference{
+		kind:        kindOf(kind),
+		identity:    identity,
+		bom:         bom,
+		annotations: copyAnnotations(annotations),
+	})
+}
+
+// checkJSONFields fails with a FieldError for the first field, in sorted order, that is not one of known.
+func checkJSONFields(prefix string, fields map[string]json.RawMessage, known ...string) error {
+	unknown := make([]string, 0)
+	for name := range fields {
+		found := false
+		for _, k := range known {
+			found = found || name == k
+		}
+		if !found {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	return &FieldError{Field: prefix + unknown[0], Err: ErrUnknownField}
+}
+
+// decodeJSONField decodes the required field name of fields, whose path starts with prefix, into v.
+func decodeJSONField(prefix, name string, fields map[string]json.RawMessage, v interface{}) error {
+	raw, ok := fields[name]
+	if !ok || string(raw) == "null" {
+		return &FieldError{Field: prefix + name, Err: ErrMissingField}
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return &FieldError{Field: prefix + name, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package omnibor
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanonicalJSON(t *testing.T) {
+	bom, err := NewIdentifier("dc0be356e8c2ba26e66448d97db76ad050206574")
+	require.NoError(t, err)
+
+	gb1 := NewSha1OmniBOR()
+	require.NoError(t, gb1.AddReference([]byte("independent"), nil))
+	require.NoError(t, gb1.AddReference([]byte("hello2"), bom))
+
+	gb2 := NewSha1OmniBOR()
+	require.NoError(t, gb2.AddReference([]byte("hello2"), bom))
+	require.NoError(t, gb2.AddReference([]byte("independent"), nil))
+
+	expected := `{"algorithm":"sha1","identity":"` + gb1.Identity() + `","references":[` +
+		`{"bom":"dc0be356e8c2ba26e66448d97db76ad050206574","identity":"23294b0610492cf55c1c4835216f20d376a287dd"},` +
+		`{"identity":"be78cc5602c5457f144a67e574b8f98b9dc2a1a0"}]}`
+	assert.Equal(t, expected, string(gb1.CanonicalJSON()))
+	assert.Equal(t, gb1.CanonicalJSON(), gb2.CanonicalJSON())
+
+	marshalled, err := json.Marshal(gb2)
+	assert.NoError(t, err)
+	assert.Equal(t, gb1.CanonicalJSON(), marshalled)
+}
+
+func TestCanonicalJSONEmpty(t *testing.T) {
+	gb := NewSha256OmniBOR()
+	assert.Equal(t, `{"algorithm":"sha256","identity":"`+gb.Identity()+`","references":[]}`, string(gb.CanonicalJSON()))
+}
+
+func TestWriteJSONL(t *testing.T) {
+	bom, err := NewIdentifier("dc0be356e8c2ba26e66448d97db76ad050206574")
+	require.NoError(t, err)
+
+	gb := NewSha1OmniBOR()
+	require.NoError(t, gb.AddReference([]byte("world"), nil))
+	require.NoError(t, gb.AddReference([]byte("independent"), nil))
+	require.NoError(t, gb.AddReference([]byte("hello2"), bom))
+
+	var buf bytes.Buffer
+	require.NoError(t, gb.WriteJSONL(&buf))
+	assert.Equal(t, `{"identity":"04fea06420ca60892f73becee3614f6d023a4b7f"}`+"\n"+
+		`{"bom":"dc0be356e8c2ba26e66448d97db76ad050206574","identity":"23294b0610492cf55c1c4835216f20d376a287dd"}`+"\n"+
+		`{"identity":"be78cc5602c5457f144a67e574b8f98b9dc2a1a0"}`+"\n", buf.String())
+
+	var decoded []jsonReference
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var ref jsonReference
+		require.NoError(t, dec.Decode(&ref))
+		decoded = append(decoded, ref)
+	}
+
+	expected := make([]jsonReference, 0)
+	for _, ref := range gb.References() {
+		r := jsonReference{Identity: ref.Identity()}
+		if ref.Bom() != nil {
+			r.Bom = ref.Bom().Identity()
+		}
+		expected = append(expected, r)
+	}
+	assert.Equal(t, expected, decoded)
+
+	buf.Reset()
+	require.NoError(t, NewSha256OmniBOR().WriteJSONL(&buf))
+	assert.Equal(t, "", buf.String())
+}
+
+func TestParseJSON(t *testing.T) {
+	bom, err := NewIdentifier("dc0be356e8c2ba26e66448d97db76ad050206574")
+	require.NoError(t, err)
+	annotated, err := NewAnnotatedReference("be78cc5602c5457f144a67e574b8f98b9dc2a1a0", nil, map[string]string{"mode": "100755"})
+	require.NoError(t, err)
+
+	for _, gb := range []ArtifactTree{NewSha1OmniBOR(), NewSha256OmniBOR()} {
+		require.NoError(t, gb.AddReference([]byte("hello2"), bom))
+		require.NoError(t, gb.AddReference([]byte("world"), nil))
+
+		parsed, err := ParseJSON(bytes.NewReader(gb.CanonicalJSON()))
+		require.NoError(t, err)
+		assert.Equal(t, gb.String(), parsed.String())
+	}
+
+	gb := NewSha1OmniBOR()
+	require.NoError(t, gb.AddReferences([]Reference{annotated}))
+	parsed, err := ParseJSON(bytes.NewReader(gb.CanonicalJSON()))
+	require.NoError(t, err)
+	assert.Equal(t, gb.String(), parsed.String())
+
+	// the identity is optional
+	parsed, err = ParseJSON(bytes.NewReader([]byte(`{"algorithm":"sha1","references":[]}`)))
+	require.NoError(t, err)
+	assert.True(t, parsed.IsEmpty())
+}
+
+func TestParseJSONInvalid(t *testing.T) {
+	const hello = "b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0"
+	for input, expected := range map[string]struct {
+		field string
+		err   error
+	}{
+		`{"algorithm":"sha1","references":[],"extra":1}`:                              {"extra", ErrUnknownField},
+		`{"algorithm":"sha1","references":[{"identity":"` + hello + `","path":"a"}]}`: {"references[0].path", ErrUnknownField},
+		`{"references":[]}`:                                                                                         {"algorithm", ErrMissingField},
+		`{"algorithm":"md5","references":[]}`:                                                                       {"algorithm", ErrUnknownAlgorithm},
+		`{"algorithm":"sha1"}`:                                                                                      {"references", ErrMissingField},
+		`{"algorithm":"sha1","references":[{"bom":"` + hello + `"}]}`:                                               {"references[0].identity", ErrMissingField},
+		`{"algorithm":"sha1","references":[{"identity":"` + hello[:39] + `g"}]}`:                                    {"references[0].identity", ErrInvalidHex},
+		`{"algorithm":"sha1","references":[{"identity":"` + strings.ToUpper(hello) + `"}]}`:                         {"references[0].identity", ErrInvalidHex},
+		`{"algorithm":"sha256","references":[{"identity":"` + hello + `"}]}`:                                        {"references[0].identity", ErrAlgorithmMismatch},
+		`{"algorithm":"sha1","references":[{"identity":"` + hello + `"},{"identity":"` + hello + `","bom":"abc"}]}`: {"references[1].bom", ErrInvalidHashLength},
+		`{"algorithm":"sha1","references":[{"identity":"` + hello + `","annotations":{"a":"b c"}}]}`:                {"references[0].annotations", ErrInvalidAnnotation},
+		`{"algorithm":"sha1","identity":"` + hello + `","references":[]}`:                                           {"identity", ErrIdentityMismatch},
+	} {
+		_, err := ParseJSON(strings.NewReader(input))
+		var fieldErr *FieldError
+		if assert.True(t, errors.As(err, &fieldErr), input) {
+			assert.Equal(t, expected.field, fieldErr.Field, input)
+			assert.True(t, errors.Is(err, expected.err), "%s: %v", input, err)
+		}
+	}
+
+	_, err := ParseJSON(strings.NewReader(`{"algorithm":"sha1","references":[]} {}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,36 @@
+package omnibor
+
+import (
+	"fmt"
+)
+
+// ObjectKind is the git object type of a referenced object. It is hashed into the gitoid header and starts
+// the object's line of the document. Every reference is a blob unless added with AddObject or AddObjectFromReader;
+// the other kinds are reserved by the spec for future artifact kinds and are rejected by tools that only know blobs.
+type ObjectKind string
+
+const (
+	KindBlob   ObjectKind = "blob"
+	KindTree   ObjectKind = "tree"
+	KindCommit ObjectKind = "commit"
+	KindTag    ObjectKind = "tag"
+)
+
+// kindOf returns the kind a reference stores for kind, empty for a blob so that plain references compare equal
+// however they were created.
+func kindOf(kind ObjectKind) ObjectKind {
+	if kind == KindBlob {
+		return ""
+	}
+	return kind
+}
+
+// validateKind checks that kind is one of the git object types.
+func validateKind(kind ObjectKind) error {
+	switch kind {
+	case KindBlob, KindTree, KindCommit, KindTag:
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownObjectKind, kind)
+	}
+}
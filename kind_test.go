@@ -0,0 +1,47 @@
+package omnibor
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/edwarnicke/gitoid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddObjectKind(t *testing.T) {
+	gb := NewSha1OmniBOR()
+	require.NoError(t, gb.AddObject(KindTree, []byte("hello"), nil))
+	require.NoError(t, gb.AddReference([]byte("world"), nil))
+
+	expected, err := gitoid.New(bytes.NewReader([]byte("hello")),
+		gitoid.WithGitObjectType(gitoid.TREE), gitoid.WithContentLength(5))
+	require.NoError(t, err)
+
+	refs := gb.References()
+	require.Len(t, refs, 2)
+	var tree Reference
+	for _, ref := range refs {
+		if ref.Kind() == KindTree {
+			tree = ref
+		}
+	}
+	require.NotNil(t, tree)
+	assert.Equal(t, expected.String(), tree.Identity())
+	assert.True(t, expected.Equal(tree.GitOID()))
+	assert.Contains(t, gb.String(), "tree "+expected.String()+"\n")
+	assert.Contains(t, gb.String(), "blob 04fea06420ca60892f73becee3614f6d023a4b7f\n")
+
+	parsed, err := Parse(strings.NewReader(gb.String()))
+	require.NoError(t, err)
+	assert.Equal(t, gb.String(), parsed.String())
+	assert.Equal(t, gb.Identity(), parsed.Identity())
+
+	err = gb.AddObject(ObjectKind("dir"), []byte("hello"), nil)
+	assert.True(t, errors.Is(err, ErrUnknownObjectKind))
+
+	_, err = Parse(strings.NewReader("dir " + expected.String() + "\n"))
+	assert.True(t, errors.Is(err, ErrMalformedReference))
+}
@@ -0,0 +1,44 @@
+package omnibor
+
+import (
+	"fmt"
+)
+
+// InputManifest is the artifact tree a compiler embeds in its output: a single reference to the output artifact
+// linked through its bom to Inputs, the tree of everything the output was built from.
+// Both trees must be stored for the output's build graph to be followed, Inputs first.
+type InputManifest struct {
+	ArtifactTree
+
+	// Inputs is the tree of the inputs, the bom of the output's reference.
+	Inputs ArtifactTree
+}
+
+// BuildInputManifest builds the two level tree of a build step in one call, as done in two by hand:
+// the inputs are added to a tree, and the output is referenced in a second tree linked to the first.
+// The hash algorithm is that of the output identity, every input must use it too.
+// The returned tree is an *InputManifest, giving access to the tree of the inputs.
+func BuildInputManifest(inputs []Reference, output Identifier) (ArtifactTree, error) {
+	if output == nil {
+		return nil, fmt.Errorf("%w: no output", ErrMalformedReference)
+	}
+	outputTree, err := newTreeForLength(len(output.Identity()))
+	if err != nil {
+		return nil, fmt.Errorf("output %q: %w", output.Identity(), err)
+	}
+	inputTree, err := newTreeForAlgorithm(outputTree.hashType)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := inputTree.AddReferences(inputs); err != nil {
+		return nil, err
+	}
+	if err := outputTree.addParsedReference(reference{identity: output.Identity(), bom: inputTree}); err != nil {
+		return nil, fmt.Errorf("output: %w", err)
+	}
+	return &InputManifest{
+		ArtifactTree: outputTree,
+		Inputs:       inputTree,
+	}, nil
+}
@@ -0,0 +1,70 @@
+package omnibor
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildInputManifest(t *testing.T) {
+	for algo, expected := range map[HashAlgorithm][]string{
+		Sha1: {
+			"04fea06420ca60892f73becee3614f6d023a4b7f",
+			"b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0",
+			"23294b0610492cf55c1c4835216f20d376a287dd",
+			"dc0be356e8c2ba26e66448d97db76ad050206574",
+		},
+		Sha256: {
+			"8aec4e4876f854f688d0ebfc8f37598f38e5fd6903cccc850ca36591175aeb60",
+			"8df3dab4ddfa6eb2a34065cda27d95af2709d4d2658e1b5fbd145822acf42b28",
+			"1861fbb8d1e47ae6328232968bac77acfd7c9afa2f179afbcdae3fd1b0658a60",
+			"e32e7e7761709be17ef573556a82960d489ddf0092424f7db1c91d8363dde822",
+		},
+	} {
+		inputs := make([]Reference, 0, 2)
+		for _, id := range expected[:2] {
+			ref, err := NewReference(id, nil)
+			require.NoError(t, err)
+			inputs = append(inputs, ref)
+		}
+		output, err := NewIdentifier(expected[2])
+		require.NoError(t, err)
+
+		gb, err := BuildInputManifest(inputs, output)
+		require.NoError(t, err)
+		assert.Equal(t, "blob "+expected[2]+" bom "+expected[3]+"\n", gb.String(), algo)
+
+		manifest, ok := gb.(*InputManifest)
+		require.True(t, ok)
+		assert.Equal(t, "blob "+expected[0]+"\nblob "+expected[1]+"\n", manifest.Inputs.String())
+		assert.Equal(t, expected[3], manifest.Inputs.Identity())
+
+		// the same trees built by hand as in the nested workflow
+		inputTree, err := newTreeForAlgorithm(algo)
+		require.NoError(t, err)
+		require.NoError(t, inputTree.AddReference([]byte("hello"), nil))
+		require.NoError(t, inputTree.AddReference([]byte("world"), nil))
+		outputTree, err := newTreeForAlgorithm(algo)
+		require.NoError(t, err)
+		require.NoError(t, outputTree.AddReference([]byte("hello2"), inputTree))
+		assert.Equal(t, outputTree.Identity(), gb.Identity())
+	}
+}
+
+func TestBuildInputManifestInvalid(t *testing.T) {
+	output, err := NewIdentifier("23294b0610492cf55c1c4835216f20d376a287dd")
+	require.NoError(t, err)
+	input, err := NewReference("8aec4e4876f854f688d0ebfc8f37598f38e5fd6903cccc850ca36591175aeb60", nil)
+	require.NoError(t, err)
+
+	_, err = BuildInputManifest([]Reference{input}, output)
+	assert.True(t, errors.Is(err, ErrAlgorithmMismatch))
+
+	_, err = BuildInputManifest(nil, nil)
+	assert.True(t, errors.Is(err, ErrMalformedReference))
+
+	_, err = BuildInputManifest(nil, identifier{identity: "2329"})
+	assert.True(t, errors.Is(err, ErrInvalidHashLength))
+}
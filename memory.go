@@ -0,0 +1,80 @@
+package omnibor
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// MemoryStore is an ObjectStore keeping objects in memory, for tests and for tools that cannot touch the filesystem.
+// It is safe for concurrent use.
+type MemoryStore struct {
+	lock    sync.Mutex
+	objects map[string][]byte
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		objects: make(map[string][]byte),
+	}
+}
+
+func (s *MemoryStore) Put(identity string, content []byte) error {
+	key, err := objectKey(identity)
+	if err != nil {
+		return err
+	}
+
+	stored := make([]byte, len(content))
+	copy(stored, content)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.objects[key] = stored
+	return nil
+}
+
+func (s *MemoryStore) Get(identity string) ([]byte, error) {
+	key, err := objectKey(identity)
+	if err != nil {
+		return nil, err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	content, ok := s.objects[key]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", identity, ErrObjectNotFound)
+	}
+	result := make([]byte, len(content))
+	copy(result, content)
+	return result, nil
+}
+
+func (s *MemoryStore) Has(identity string) bool {
+	key, err := objectKey(identity)
+	if err != nil {
+		return false
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	_, ok := s.objects[key]
+	return ok
+}
+
+// Keys returns the identities of every stored object in ascending order, it never fails.
+func (s *MemoryStore) Keys() ([]string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	keys := make([]string, 0, len(s.objects))
+	for identity := range s.objects {
+		keys = append(keys, identity)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
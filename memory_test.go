@@ -0,0 +1,68 @@
+package omnibor
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	keys, err := store.Keys()
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	child := NewSha256OmniBOR()
+	require.NoError(t, child.AddReference([]byte("hello"), nil))
+	parent := NewSha1OmniBOR()
+	require.NoError(t, parent.AddReference([]byte("world"), child))
+
+	for _, gb := range []ArtifactTree{parent, child} {
+		assert.False(t, store.Has(gb.Identity()))
+		require.NoError(t, store.Put(gb.Identity(), []byte(gb.String())))
+		assert.True(t, store.Has(gb.Identity()))
+
+		content, err := store.Get(gb.Identity())
+		assert.NoError(t, err)
+		assert.Equal(t, gb.String(), string(content))
+
+		parsed, err := Parse(bytes.NewReader(content))
+		assert.NoError(t, err)
+		assert.Equal(t, gb.Identity(), parsed.Identity())
+	}
+	keys, err = store.Keys()
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"045ec8de70efb3ac502eafba875bcb21b6eddb5ab09025a9de7187948ffebb68",
+		"f110215293d115300ccdb5da1c827820232d425a",
+	}, keys)
+
+	path, err := SliceTo(store, parent.Identity(), "8aec4e4876f854f688d0ebfc8f37598f38e5fd6903cccc850ca36591175aeb60")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{parent.Identity(), child.Identity()}, path)
+
+	_, err = store.Get("04fea06420ca60892f73becee3614f6d023a4b7f")
+	assert.True(t, errors.Is(err, ErrObjectNotFound))
+
+	err = store.Put("04fea0", []byte("hello"))
+	assert.True(t, errors.Is(err, ErrInvalidHashLength))
+}
+
+func TestMemoryStoreCopies(t *testing.T) {
+	store := NewMemoryStore()
+	content := []byte("blob 04fea06420ca60892f73becee3614f6d023a4b7f\n")
+	require.NoError(t, store.Put("bc9d4e98e0a8e4b68a8ef9f1a6b0a7ad6b1a6a32", content))
+	content[0] = 'X'
+
+	stored, err := store.Get("bc9d4e98e0a8e4b68a8ef9f1a6b0a7ad6b1a6a32")
+	require.NoError(t, err)
+	assert.Equal(t, byte('b'), stored[0])
+	stored[0] = 'Y'
+
+	stored, err = store.Get("bc9d4e98e0a8e4b68a8ef9f1a6b0a7ad6b1a6a32")
+	require.NoError(t, err)
+	assert.Equal(t, byte('b'), stored[0])
+}
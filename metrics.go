@@ -0,0 +1,35 @@
+package omnibor
+
+import (
+	"time"
+)
+
+// Metrics reports where AddTree and AddFS spent their time, see WithMetrics.
+// There is no sort figure: adding files does not order the references, they are sorted each time a document
+// is rendered, by String, Identity and the encodings built on them, so time those calls to measure sorting.
+type Metrics struct {
+	// Files is the number of files added.
+	Files int
+
+	// Bytes is the total size of the files added, including those taken from a hash cache.
+	Bytes int64
+
+	// Total is the wall time of the calls.
+	Total time.Duration
+
+	// Walk is the wall time of the directory walks. The walk runs alongside hashing and waits for idle workers,
+	// so a walk close to Total means hashing is the bottleneck.
+	Walk time.Duration
+
+	// Hash is the time spent reading and hashing files, summed across workers, so it may exceed Total.
+	Hash time.Duration
+}
+
+// WithMetrics makes AddTree and AddFS add what they did to m once they return, whether or not they fail.
+// Passing the same Metrics to several calls accumulates their figures. m must not be read while a call is running.
+// Without this option nothing is measured.
+func WithMetrics(m *Metrics) Option {
+	return func(o *options) {
+		o.metrics = m
+	}
+}
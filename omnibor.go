@@ -17,9 +17,11 @@ package omnibor
 
 import (
 	"bytes"
+	"context"
 	"encoding/hex"
 	"fmt"
 	"io"
+	"io/fs"
 	"sort"
 	"strings"
 	"sync"
@@ -38,39 +40,227 @@ type ArtifactTree interface {
 	// It returns an error if the SHA1 or SHA256 implementations fails.
 	AddReference(obj []byte, bom Identifier) error
 
+	// AddReferenceChecked is AddReference for content whose length is also recorded elsewhere, for example
+	// in metadata that may be stale. Nothing is added and a *ContentLengthError is returned if declaredLen
+	// is not the length of obj.
+	AddReferenceChecked(obj []byte, declaredLen int64, bom Identifier) error
+
 	// AddReferenceFromReader adds a SHA1+SHA256 based git reference to the current OmniBOR document.
 	// The resulting reference is based on the GitRef format.
 	// The io.Reader will be continuously be read until the reader returns a non-null error.
 	// If the io.Reader returns io.EOF, the read is considered to be complete.
 	// Any other return value from Reader is an error.
 	// The object length must be included.
-	// If the amount of bytes read does not match the stated object length, an error is returned.
+	// If the amount of bytes read does not match the stated object length, a *ContentLengthError is returned.
 	AddReferenceFromReader(reader io.Reader, bom Identifier, objLength int64) error
 
+	// AddReferenceFromReaderID is AddReferenceFromReader returning the identity of the added reference,
+	// for example to link to an artifact right after hashing it.
+	AddReferenceFromReaderID(reader io.Reader, bom Identifier, objLength int64) (string, error)
+
+	// AddObject is AddReference for an object of the given kind, which is hashed into the gitoid header
+	// and rendered in place of "blob" on the object's line. An error wrapping ErrUnknownObjectKind is returned
+	// if kind is not a git object type.
+	AddObject(kind ObjectKind, obj []byte, bom Identifier) error
+
+	// AddObjectFromReader is AddReferenceFromReader for an object of the given kind, see AddObject.
+	AddObjectFromReader(kind ObjectKind, reader io.Reader, bom Identifier, objLength int64) error
+
+	// AddReferenceFromReaderUnsized adds a reference for the content of reader when its length is not known
+	// up front, as for pipes or decompressing readers. reader is consumed until io.EOF.
+	// The gitoid header includes the content length, so the whole content is buffered in memory before hashing;
+	// prefer AddReferenceFromReader when the length is known and the content may be large.
+	AddReferenceFromReaderUnsized(reader io.Reader, bom Identifier) error
+
+	// AddReferenceRange adds a reference whose content is the byte range [start, start+length) of r.
+	// This is meant for artifacts with a stable region and a volatile trailer (timestamps, signatures).
+	// The resulting gitoid is that of the sub-range alone and does not match the gitoid of the whole artifact,
+	// so it is not a standard artifact identity.
+	// An error is returned if r holds fewer than start+length bytes.
+	AddReferenceRange(r io.ReaderAt, start, length int64, bom Identifier) error
+
 	// AddExistingReference adds an existing pre-computed reference
-	// The string must be a valid gitoid identifier.
+	// The string must be a valid gitoid identifier. Nothing is added if the tree holds the same reference already.
 	AddExistingReference(s string) error
 
-	// References Returns a lsit of references in the order it will be printed.
+	// AddReferences adds pre-computed references, typically taken from another tree or a parsed document,
+	// under a single lock acquisition. Every reference is validated as by AddExistingReference before any is added,
+	// so an invalid reference leaves the tree untouched.
+	// A reference identical to one present already, in the tree or earlier in refs, is skipped; the same object
+	// linked to another bom is added, so adding the References of a tree to an empty one reproduces its identity.
+	AddReferences(refs []Reference) error
+
+	// AddExistingReferences is AddExistingReference for a batch of ids, see AddReferences.
+	AddExistingReferences(ids []string) error
+
+	// AddTree walks the directory rooted at root and adds a reference for every regular file below it.
+	// Symbolic links are resolved and the target's content is referenced. FIFOs, sockets and devices are skipped.
+	// Hashing is spread across a bounded number of workers; the first error aborts the walk.
+	AddTree(root string, opts ...Option) error
+
+	// AddTreeContext is AddTree aborting the walk and any hashing in flight once ctx is done.
+	// It returns ctx.Err() after every worker has exited; references added before that are kept.
+	AddTreeContext(ctx context.Context, root string, opts ...Option) error
+
+	// AddFS is AddTree over a virtual filesystem such as embed.FS or fstest.MapFS, walking root within fsys.
+	AddFS(fsys fs.FS, root string, opts ...Option) error
+
+	// AddTar adds a reference for every regular file of the tar archive read from r,
+	// as AddTree would for the extracted archive.
+	AddTar(r io.Reader, opts ...Option) error
+
+	// AddZip adds a reference for every file of the zip archive of the given size read from r,
+	// as AddTree would for the extracted archive. Nested archives are added as files, not expanded.
+	AddZip(r io.ReaderAt, size int64, opts ...Option) error
+
+	// RemoveReference removes every reference with the given identity, whether or not it carries a bom link.
+	// It returns true if anything was removed.
+	RemoveReference(identity string) bool
+
+	// Clone returns an independent copy of the OmniBOR using the same hash algorithm.
+	// References added to or removed from the copy do not affect the original and vice versa.
+	// Bom identifiers are shared, not copied.
+	Clone() ArtifactTree
+
+	// Reset removes every reference, keeping the hash algorithm, comparator and default options, so the tree
+	// can be reused to build another document without allocating a new one. Generations keep counting,
+	// so ReferencesSince reports references added after the reset to callers holding an earlier generation.
+	Reset()
+
+	// Contains reports whether the OmniBOR holds a reference with the given identity,
+	// whether or not it carries a bom link. Lookups take constant time, so it is cheap to check
+	// before hashing a large artifact that may already be present.
+	Contains(identity string) bool
+
+	// Get returns the reference with the given identity, in constant time like Contains.
+	// If several references share the identity, as the same object linked to different boms, the one rendered
+	// first in String is returned. It returns false if there is no such reference.
+	Get(identity string) (Reference, bool)
+
+	// Len returns the number of references in the OmniBOR document.
+	Len() int
+
+	// IsEmpty reports whether the OmniBOR holds no reference. The document of an empty tree is "", its identity
+	// is EmptySha1Identity or EmptySha256Identity, which is valid but rarely worth recording.
+	IsEmpty() bool
+
+	// Stats returns the number of references, how many of them carry a bom link,
+	// and the length of the document in bytes, without rendering the document.
+	Stats() TreeStats
+
+	// References Returns a lsit of references in the order it will be printed,
+	// or in the order of the comparator given to the constructor, see WithComparator.
 	References() []Reference
 
+	// LinkedReferences returns the references carrying a bom link, the dependency edges of the tree,
+	// in the order of References.
+	LinkedReferences() []Reference
+
+	// Identities returns the distinct identities of the references in ascending order, the flat set of gitoids
+	// in the tree without their bom links. An identity linked to several boms is listed once.
+	Identities() []string
+
+	// Graph returns the dependency graph of the document: an edge from its identity to every reference, and one
+	// from every linked reference to its bom. See BuildGraph for following bom links to other documents.
+	// An error wrapping ErrCycle is returned if the bom links of the references form a cycle.
+	Graph() (*Graph, error)
+
+	// Resolve loads the document of every bom link from store, and those of their bom links in turn,
+	// returning the tree with the full transitive closure of its build graph.
+	// An error wrapping ErrObjectNotFound is returned if a linked document is not in store,
+	// one wrapping ErrCycle if the links lead back to a document being resolved.
+	Resolve(store ObjectStore) (*ResolvedTree, error)
+
+	// Walk calls fn for every reference in the order of References, without copying the references themselves.
+	// The tree is locked for the duration of the walk, so fn must not call any method of the tree.
+	// The walk stops at the first error returned by fn, which is returned as is.
+	Walk(fn func(Reference) error) error
+
+	// Iterator returns a ReferenceIterator handing out the references in the order of String, pageSize at a time.
+	// Page sizes below one are treated as one. The iterator pages over a sorted snapshot of the references
+	// taken by Iterator, leaving the tree untouched, so references added or removed while iterating are not seen.
+	Iterator(pageSize int) ReferenceIterator
+
+	// ReferencesSince returns the references added after the given generation, in insertion order,
+	// together with the current generation.
+	// Every added reference advances the generation by one, so passing the returned generation back in
+	// on the next call yields only what was added in between. Passing 0 returns every reference.
+	ReferencesSince(generation uint64) ([]Reference, uint64)
+
 	// String Returns the string representation of the OmniBOR.
 	String() string
+
+	// Render returns the references in the order of String, laid out as configured by opts.
+	// Without options it returns exactly String. Only String is the spec's canonical document
+	// and the one Identity is computed over; other renderings are for embedding in other formats.
+	Render(opts ...RenderOption) string
+
+	// IdentityEncoded returns the identity encoded as enc instead of hex, see Encoding.
+	IdentityEncoded(enc Encoding) string
+
+	// Summary returns a one line description of the OmniBOR for logs and test failures,
+	// as in "OmniBOR(sha1, 2 refs, id=dc0be356e8c2...)", where id is the start of the identity.
+	Summary() string
+
+	// CanonicalJSON returns the JSON encoding of the OmniBOR, guaranteed to be byte for byte identical for equal trees.
+	// The document is an object with the keys "algorithm", "identity" and "references", in that order and without
+	// insignificant whitespace. References are sorted as in String and are objects with optional "annotations"
+	// and "bom" keys, an "identity" key and a "kind" key, emitted only for objects that are not blobs.
+	// All keys are emitted in lexicographic order so the encoding is stable across versions.
+	CanonicalJSON() []byte
+
+	// WriteJSONL writes every reference to w as a JSON object on its own line, in the order of String.
+	// The objects are encoded as in CanonicalJSON, with optional "annotations" and "bom" keys, an "identity" key
+	// and a "kind" key for objects that are not blobs.
+	WriteJSONL(w io.Writer) error
+
+	// WriteCompressed writes the document, as returned by String, to w as a gzip stream.
+	// The identity is that of the uncompressed document; ParseCompressed reads it back.
+	WriteCompressed(w io.Writer) error
+
+	// SectionedString returns a non-canonical rendering of the OmniBOR for human inspection.
+	// References are grouped by the leading hex digit of their identity, each group preceded by a `# x*` comment line.
+	// Identity is always computed over String, never over this rendering.
+	SectionedString() string
 }
 
 type Reference interface {
 	// Identity returns the GitRef identity of the object as a hex string.
 	Identity() string
 
+	// QualifiedIdentity returns the identity prefixed with the hash algorithm it was computed with,
+	// as in "sha1:b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0".
+	QualifiedIdentity() string
+
+	// Kind returns the git object type of the object, KindBlob unless it was added as another kind.
+	Kind() ObjectKind
+
+	// GitOID returns the gitoid of the object, giving access to its raw digest and URI.
+	// It is derived from the identity, the object is not hashed again.
+	GitOID() *gitoid.GitOID
+
 	// Bom returns an Identifier representing the dependency tree of the object represented by the Identity
 	Bom() Identifier
 
+	// Annotations returns a copy of the key-value annotations of the reference, nil if it has none.
+	// See NewAnnotatedReference for the document extension rendering them.
+	Annotations() map[string]string
+
 	// String returns a ArtifactTree entry represented by this Reference.
 	String() string
 }
 
+// referenceSorter orders references by ascending identity, comparing the lowercase hex strings byte by byte,
+// which is the order required by the spec.
+// Identities sharing a prefix are ordered by the first differing digit, a shorter identity sorting first.
+// References with the same identity (the same object built against different boms) are ordered by their
+// rendered line, so a reference without a bom comes before linked ones, and linked ones by bom identity.
+// This makes the ordering total and the rendered document independent of insertion order.
 func referenceSorter(r1, r2 Reference) bool {
-	return r1.Identity() < r2.Identity()
+	if r1.Identity() != r2.Identity() {
+		return r1.Identity() < r2.Identity()
+	}
+	return r1.String() < r2.String()
 }
 
 type by func(p1, p2 Reference) bool
@@ -84,9 +274,12 @@ func (b by) sort(refs []Reference) {
 }
 
 type reference struct {
-	hashType string
-	identity string
-	bom      Identifier
+	hashType    HashAlgorithm
+	kind        ObjectKind // empty for a blob
+	identity    string
+	bom         Identifier
+	annotations map[string]string // never modified once set, nil for a plain reference
+	generation  uint64
 }
 
 type referenceSort struct {
@@ -110,15 +303,42 @@ func (ref reference) Identity() string {
 	return ref.identity
 }
 
+func (ref reference) QualifiedIdentity() string {
+	return string(ref.hashType) + ":" + ref.identity
+}
+
+func (ref reference) Kind() ObjectKind {
+	if ref.kind == "" {
+		return KindBlob
+	}
+	return ref.kind
+}
+
+func (ref reference) GitOID() *gitoid.GitOID {
+	gitOID, err := gitoid.FromURI("gitoid:" + string(ref.Kind()) + ":" + string(ref.hashType) + ":" + ref.identity)
+	if err != nil {
+		// identities are validated hex when the reference is created
+		panic(err)
+	}
+	return gitOID
+}
+
 func (ref reference) Bom() Identifier {
-	return ref.Bom()
+	return ref.bom
+}
+
+func (ref reference) Annotations() map[string]string {
+	return copyAnnotations(ref.annotations)
 }
 
 func (ref reference) String() string {
-	res := fmt.Sprintf("blob %s", ref.identity)
+	res := fmt.Sprintf("%s %s", ref.Kind(), ref.identity)
 	if ref.bom != nil {
 		res = fmt.Sprintf("%s bom %s", res, ref.bom.Identity())
 	}
+	if ref.annotations != nil {
+		res += renderAnnotations(ref.annotations)
+	}
 
 	res = res + "\n"
 	return res
@@ -129,33 +349,61 @@ type Identifier interface {
 }
 
 type omniBor struct {
-	lock          sync.Mutex
-	gitRefs       []Reference
-	gitoidOptions []gitoid.Option
-	hashType      string
+	lock       sync.Mutex
+	gitRefs    []Reference
+	identities map[string][]Reference // references per identity, in insertion order
+	hashType   HashAlgorithm
+	generation uint64
+	comparator func(r1, r2 Reference) bool // order of References and Walk, nil for the canonical order
+	defaults   []Option                    // ingestion options set by NewFromConfig, applied before those of a call
+	hashers    *sync.Pool                  // hashers set by WithHashFunc, nil for those of hasherPools
 }
 
 // NewSha1OmniBOR creates a new ArtifactTree object.
 // Thread Safety: none, apply your own controls.
 //
-// Adding duplicate objects with the same Reference identity results in only one Reference entry.
+// Adding duplicate objects with the same Reference identity results in only one Reference entry,
+// unless they were built against different boms, in which case the object is listed once per bom.
 // References are sorted in ascending order based on their UTF-8 values.
 //
 // Implementation details:
-// Adding a Reference is O(n) to discover duplicates.
+// Looking up an identity, as done to discover duplicates, is O(1).
 // Generating a ArtifactTree is O(n*log(n)) as it sorts the existing refs.
-func NewSha1OmniBOR() ArtifactTree {
-	return &omniBor{
-		hashType: "sha1",
+func NewSha1OmniBOR(opts ...TreeOption) ArtifactTree {
+	return newOmniBor(&omniBor{
+		hashType: Sha1,
+	}, opts)
+}
+
+func NewSha256OmniBOR(opts ...TreeOption) ArtifactTree {
+	return newOmniBor(&omniBor{
+		hashType: Sha256,
+	}, opts)
+}
+
+// NewOmniBORFromExisting creates an ArtifactTree holding the given pre-computed gitoids.
+// The hash algorithm is inferred from the length of the first id, every other id must have the same length.
+// Without ids an empty sha1 tree is returned.
+func NewOmniBORFromExisting(ids ...string) (ArtifactTree, error) {
+	if len(ids) == 0 {
+		return NewSha1OmniBOR(), nil
+	}
+
+	gb, err := newTreeForLength(len(ids[0]))
+	if err != nil {
+		return nil, err
 	}
+	if err := gb.AddExistingReferences(ids); err != nil {
+		return nil, err
+	}
+	return gb, nil
 }
 
-func NewSha256OmniBOR() ArtifactTree {
-	options := []gitoid.Option{gitoid.WithSha256()}
-	return &omniBor{
-		gitoidOptions: options,
-		hashType:      "sha256",
+func newOmniBor(srv *omniBor, opts []TreeOption) *omniBor {
+	for _, opt := range opts {
+		opt(srv)
 	}
+	return srv
 }
 
 func (srv *omniBor) AddReference(obj []byte, bom Identifier) error {
@@ -163,115 +411,503 @@ func (srv *omniBor) AddReference(obj []byte, bom Identifier) error {
 	return srv.addGitRef(reader, bom, int64(len(obj)))
 }
 
+func (srv *omniBor) AddReferenceChecked(obj []byte, declaredLen int64, bom Identifier) error {
+	if declaredLen < 0 {
+		return fmt.Errorf("%w: negative content length %d", ErrInvalidRange, declaredLen)
+	}
+	if declaredLen != int64(len(obj)) {
+		return &ContentLengthError{Expected: declaredLen, Actual: int64(len(obj))}
+	}
+	return srv.AddReference(obj, bom)
+}
+
 func (srv *omniBor) AddReferenceFromReader(reader io.Reader, bom Identifier, objLength int64) error {
 	return srv.addGitRef(reader, bom, objLength)
 }
 
+func (srv *omniBor) AddReferenceFromReaderID(reader io.Reader, bom Identifier, objLength int64) (string, error) {
+	return srv.addGitRefID(reader, bom, objLength)
+}
+
+func (srv *omniBor) AddObject(kind ObjectKind, obj []byte, bom Identifier) error {
+	return srv.AddObjectFromReader(kind, bytes.NewReader(obj), bom, int64(len(obj)))
+}
+
+func (srv *omniBor) AddObjectFromReader(kind ObjectKind, reader io.Reader, bom Identifier, objLength int64) error {
+	if err := validateKind(kind); err != nil {
+		return err
+	}
+	_, err := srv.addObjectID(kind, reader, bom, objLength, copyBuffers)
+	return err
+}
+
+func (srv *omniBor) AddReferenceFromReaderUnsized(reader io.Reader, bom Identifier) error {
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	return srv.AddReference(content, bom)
+}
+
+func (srv *omniBor) AddReferenceRange(r io.ReaderAt, start, length int64, bom Identifier) error {
+	if start < 0 || length < 0 {
+		return fmt.Errorf("%w: start %d, length %d", ErrInvalidRange, start, length)
+	}
+	return srv.addGitRef(io.NewSectionReader(r, start, length), bom, length)
+}
+
 func (srv *omniBor) AddExistingReference(input string) error {
-	// if srv is using sha1, check that the input is a valid hex sha1 and length
-	// if srv is in sha256 mode, set hashLength to the length of a sha256 hash
-	hashLength := 40
-	if srv.hashType == "sha256" {
-		hashLength = 64
+	return srv.addParsedReference(reference{identity: input})
+}
+
+// addParsedReference adds a reference to a pre-computed identity, optionally linked to a bom and annotated,
+// after validating it against the tree's hash type. As for hashed content, only an identical reference is skipped,
+// so a document listing an object once per bom reads back with the identity it was written with.
+func (srv *omniBor) addParsedReference(ref reference) error {
+	if err := srv.validateReference(ref.identity, ref.bom); err != nil {
+		return err
 	}
 
-	if len(input) != hashLength {
-		return fmt.Errorf("invalid hash length: %d", len(input))
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+	srv.appendDistinct(ref)
+	return nil
+}
+
+// validateReference checks that input is a gitoid computed with the tree's hash algorithm and bom, if set, a valid gitoid.
+func (srv *omniBor) validateReference(input string, bom Identifier) error {
+	if len(input) != srv.hashType.HexLength() {
+		if other, err := newTreeForLength(len(input)); err == nil {
+			return fmt.Errorf("%w: %s tree given a %s identity", ErrAlgorithmMismatch, srv.hashType, other.hashType)
+		}
+		return fmt.Errorf("%w: %d", ErrInvalidHashLength, len(input))
 	}
-	if _, err := hex.DecodeString(input); err != nil {
+	if err := validateHex(input); err != nil {
 		return err
 	}
+	return validateBom(bom)
+}
 
-	ref := reference{
-		identity: input,
+// appendDistinct stores ref unless an identical reference, of the same kind, identity, bom and annotations,
+// is present already, so hashing the same content twice lists it once while the object may still be listed
+// once per bom it was built against. The caller must hold srv.lock.
+func (srv *omniBor) appendDistinct(ref reference) {
+	line := ref.String()
+	for _, existing := range srv.identities[ref.identity] {
+		if existing.String() == line {
+			return
+		}
 	}
+	srv.appendReference(ref)
+}
 
-	// check if the input is already in the gitRefs list
-	for _, existingRef := range srv.gitRefs {
-		if existingRef.Identity() == input {
-			return nil
+func (srv *omniBor) AddReferences(refs []Reference) error {
+	validated := make([]reference, 0, len(refs))
+	for i, ref := range refs {
+		if err := srv.validateReference(ref.Identity(), ref.Bom()); err != nil {
+			return fmt.Errorf("reference %d: %w", i, err)
+		}
+		annotations := ref.Annotations()
+		if err := validateAnnotations(annotations); err != nil {
+			return fmt.Errorf("reference %d: %w", i, err)
 		}
+		if err := validateKind(ref.Kind()); err != nil {
+			return fmt.Errorf("reference %d: %w", i, err)
+		}
+		validated = append(validated, reference{
+			kind:        kindOf(ref.Kind()),
+			identity:    ref.Identity(),
+			bom:         ref.Bom(),
+			annotations: copyAnnotations(annotations),
+		})
 	}
 
 	srv.lock.Lock()
-	srv.gitRefs = append(srv.gitRefs, ref)
-	srv.lock.Unlock()
+	defer srv.lock.Unlock()
+	for _, ref := range validated {
+		srv.appendDistinct(ref)
+	}
+	return nil
+}
+
+func (srv *omniBor) AddExistingReferences(ids []string) error {
+	for i, id := range ids {
+		if err := srv.validateReference(id, nil); err != nil {
+			return fmt.Errorf("id %d: %w", i, err)
+		}
+	}
 
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+	for _, id := range ids {
+		srv.appendDistinct(reference{
+			identity: id,
+		})
+	}
 	return nil
 }
 
 func (srv *omniBor) addGitRef(reader io.Reader, bom Identifier, length int64) error {
-	// add an initial option specifying the length
-	options := []gitoid.Option{
-		gitoid.WithContentLength(length),
-	}
+	_, err := srv.addGitRefID(reader, bom, length)
+	return err
+}
 
-	// populate any options we need
-	for _, option := range srv.gitoidOptions {
-		options = append(options, option)
+// addGitRefID hashes length bytes of reader, adds the reference and returns its identity.
+func (srv *omniBor) addGitRefID(reader io.Reader, bom Identifier, length int64) (string, error) {
+	return srv.addObjectID(KindBlob, reader, bom, length, copyBuffers)
+}
+
+// addObjectID is addGitRefID for an object of the given kind, hashed with copy buffers from buffers.
+func (srv *omniBor) addObjectID(kind ObjectKind, reader io.Reader, bom Identifier, length int64, buffers *sync.Pool) (string, error) {
+	if err := validateBom(bom); err != nil {
+		return "", err
 	}
-	identity, err := gitoid.New(reader, options...)
+
+	identity, err := srv.hashObject(kind, reader, length, buffers)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	ref := reference{
-		identity: identity.String(),
+		kind:     kindOf(kind),
+		identity: identity,
 		bom:      bom,
 	}
 
 	srv.lock.Lock()
-	srv.gitRefs = append(srv.gitRefs, ref)
+	srv.appendDistinct(ref)
 	srv.lock.Unlock()
-	return nil
+	return identity, nil
+}
+
+// appendReference stamps ref with the next generation and stores it.
+// The caller must hold srv.lock.
+func (srv *omniBor) appendReference(ref reference) {
+	srv.generation++
+	ref.generation = srv.generation
+	ref.hashType = srv.hashType
+	srv.gitRefs = append(srv.gitRefs, ref)
+	if srv.identities == nil {
+		srv.identities = make(map[string][]Reference)
+	}
+	srv.identities[ref.identity] = append(srv.identities[ref.identity], ref)
+}
+
+func (srv *omniBor) RemoveReference(identity string) bool {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	kept := srv.gitRefs[:0]
+	for _, ref := range srv.gitRefs {
+		if ref.Identity() != identity {
+			kept = append(kept, ref)
+		}
+	}
+	removed := len(kept) != len(srv.gitRefs)
+	for i := len(kept); i < len(srv.gitRefs); i++ {
+		srv.gitRefs[i] = nil
+	}
+	srv.gitRefs = kept
+	delete(srv.identities, identity)
+	return removed
+}
+
+func (srv *omniBor) Reset() {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	for i := range srv.gitRefs {
+		srv.gitRefs[i] = nil
+	}
+	srv.gitRefs = srv.gitRefs[:0]
+	for identity := range srv.identities {
+		delete(srv.identities, identity)
+	}
+}
+
+func (srv *omniBor) Clone() ArtifactTree {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	clone := &omniBor{
+		gitRefs:    make([]Reference, len(srv.gitRefs)),
+		identities: make(map[string][]Reference, len(srv.identities)),
+		hashType:   srv.hashType,
+		generation: srv.generation,
+		comparator: srv.comparator,
+		defaults:   srv.defaults,
+		hashers:    srv.hashers,
+	}
+	copy(clone.gitRefs, srv.gitRefs)
+	for identity, refs := range srv.identities {
+		clone.identities[identity] = append([]Reference(nil), refs...)
+	}
+	return clone
+}
+
+func (srv *omniBor) Contains(identity string) bool {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+	return len(srv.identities[identity]) > 0
+}
+
+func (srv *omniBor) Get(identity string) (Reference, bool) {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	refs := srv.identities[identity]
+	if len(refs) == 0 {
+		return nil, false
+	}
+	first := refs[0]
+	for _, ref := range refs[1:] {
+		if referenceSorter(ref, first) {
+			first = ref
+		}
+	}
+	return first, true
+}
+
+func (srv *omniBor) Len() int {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+	return len(srv.gitRefs)
+}
+
+func (srv *omniBor) IsEmpty() bool {
+	return srv.Len() == 0
+}
+
+// sortedReferences returns a sorted copy of the references, leaving srv.gitRefs untouched.
+// The caller must hold srv.lock.
+func (srv *omniBor) sortedReferences() []Reference {
+	result := make([]Reference, len(srv.gitRefs))
+	copy(result, srv.gitRefs)
+	by(referenceSorter).sort(result)
+	return result
+}
+
+// orderedReferences sorts refs in place by the comparator of srv, falling back to the canonical order.
+func (srv *omniBor) orderedReferences(refs []Reference) {
+	if srv.comparator != nil {
+		sort.SliceStable(refs, func(i, j int) bool {
+			return srv.comparator(refs[i], refs[j])
+		})
+		return
+	}
+	by(referenceSorter).sort(refs)
 }
 
 func (srv *omniBor) References() []Reference {
 	srv.lock.Lock()
-	by(referenceSorter).sort(srv.gitRefs)
-	result := make([]Reference, 0, len(srv.gitRefs))
+	defer srv.lock.Unlock()
+
+	result := make([]Reference, len(srv.gitRefs))
+	copy(result, srv.gitRefs)
+	srv.orderedReferences(result)
+	return result
+}
+
+func (srv *omniBor) LinkedReferences() []Reference {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	result := make([]Reference, 0)
+	for _, ref := range srv.gitRefs {
+		if ref.Bom() != nil {
+			result = append(result, ref)
+		}
+	}
+	srv.orderedReferences(result)
+	return result
+}
+
+func (srv *omniBor) Identities() []string {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	result := make([]string, 0, len(srv.identities))
+	for identity, refs := range srv.identities {
+		if len(refs) > 0 {
+			result = append(result, identity)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+func (srv *omniBor) Walk(fn func(Reference) error) error {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	// sort a copy, srv.gitRefs stays in insertion order whatever the comparator
+	refs := make([]Reference, len(srv.gitRefs))
+	copy(refs, srv.gitRefs)
+	srv.orderedReferences(refs)
+	for _, ref := range refs {
+		if err := fn(ref); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (srv *omniBor) ReferencesSince(generation uint64) ([]Reference, uint64) {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	result := make([]reference, 0)
 	for _, ref := range srv.gitRefs {
-		result = append(result, ref)
+		if r := ref.(reference); r.generation > generation {
+			result = append(result, r)
+		}
 	}
-	srv.lock.Unlock()
-	return srv.gitRefs
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].generation < result[j].generation
+	})
+
+	refs := make([]Reference, 0, len(result))
+	for _, ref := range result {
+		refs = append(refs, ref)
+	}
+	return refs, srv.generation
 }
 
 func (srv *omniBor) String() string {
 	srv.lock.Lock()
-	by(referenceSorter).sort(srv.gitRefs)
-	refs := make([]string, 0)
-	for _, ref := range srv.gitRefs {
+	refs := make([]string, 0, len(srv.gitRefs))
+	for _, ref := range srv.sortedReferences() {
 		refs = append(refs, ref.String())
 	}
 	srv.lock.Unlock()
 	return strings.Join(refs, "")
 }
 
-func (srv *omniBor) gitRef() string {
-	generated := srv.String()
-	// add an initial option specifying the length
-	options := []gitoid.Option{
-		gitoid.WithContentLength(int64(len(generated))),
-	}
-
-	// populate any options we need
-	for _, option := range srv.gitoidOptions {
-		options = append(options, option)
+func (srv *omniBor) SectionedString() string {
+	srv.lock.Lock()
+	var sb strings.Builder
+	section := byte(0)
+	for _, ref := range srv.sortedReferences() {
+		if lead := ref.Identity()[0]; lead != section {
+			section = lead
+			sb.WriteString(fmt.Sprintf("# %c*\n", lead))
+		}
+		sb.WriteString(ref.String())
 	}
+	srv.lock.Unlock()
+	return sb.String()
+}
 
-	res, err := gitoid.New(bytes.NewBuffer([]byte(generated)), options...)
+func (srv *omniBor) gitRef() string {
+	generated := srv.String()
+	res, err := srv.hash(bytes.NewBufferString(generated), int64(len(generated)))
 	if err != nil {
 		// we should only see this if the runtime was fundamentally broken
 		panic(err)
 	}
-	return res.String()
+	return res
 }
 
 func (srv *omniBor) Identity() string {
 	return srv.gitRef()
 }
 
+// newTreeForLength returns an empty tree using the hash algorithm whose hex digests have the given length.
+func newTreeForLength(length int) (*omniBor, error) {
+	switch length {
+	case 40:
+		return NewSha1OmniBOR().(*omniBor), nil
+	case 64:
+		return NewSha256OmniBOR().(*omniBor), nil
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrInvalidHashLength, length)
+	}
+}
+
+// validateHex checks that s is lowercase hex, the only form a gitoid takes in a document.
+func validateHex(s string) error {
+	if _, err := hex.DecodeString(s); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidHex, err)
+	}
+	if s != strings.ToLower(s) {
+		return fmt.Errorf("%w: not lowercase", ErrInvalidHex)
+	}
+	return nil
+}
+
+// validateBom checks that bom, if set, is a lowercase sha1 or sha256 gitoid so that it renders as a valid document line.
+// The bom may use a different hash algorithm than the tree linking to it.
+func validateBom(bom Identifier) error {
+	if bom == nil {
+		return nil
+	}
+	if _, err := identityAlgorithm(bom.Identity()); err != nil {
+		return fmt.Errorf("bom %q: %w", bom.Identity(), err)
+	}
+	return nil
+}
+
+// identityAlgorithm checks that identity is a lowercase sha1 or sha256 gitoid and returns the algorithm it was computed with.
+func identityAlgorithm(identity string) (HashAlgorithm, error) {
+	gb, err := newTreeForLength(len(identity))
+	if err != nil {
+		return "", err
+	}
+	if err := validateHex(identity); err != nil {
+		return "", err
+	}
+	return gb.hashType, nil
+}
+
+// NewReference returns a reference to the object with the given identity, linked to bom if it is not nil,
+// for building trees and documents outside of this package, see AddReferences.
+// identity and the identity of bom must be lowercase sha1 or sha256 gitoids, the two may use different algorithms.
+// The returned Reference is immutable.
+func NewReference(identity string, bom Identifier) (Reference, error) {
+	algo, err := identityAlgorithm(identity)
+	if err != nil {
+		return nil, fmt.Errorf("%q: %w", identity, err)
+	}
+	if err := validateBom(bom); err != nil {
+		return nil, err
+	}
+	return reference{
+		hashType: algo,
+		identity: identity,
+		bom:      bom,
+	}, nil
+}
+
+// NormalizeReferences returns refs the way a tree of algo holds them, for ingestion paths validating a batch of
+// references before using them: identities and bom identities lowercased, every reference validated as
+// AddReferences does, identical references kept once, an object linked to different boms kept once per bom,
+// and the result in the canonical order of String.
+// An error naming the offending reference is returned if algo is unknown or an identity is not a gitoid of algo.
+func NormalizeReferences(refs []Reference, algo HashAlgorithm) ([]Reference, error) {
+	gb, err := newTreeForAlgorithm(algo)
+	if err != nil {
+		return nil, err
+	}
+
+	lowered := make([]Reference, 0, len(refs))
+	for _, ref := range refs {
+		bom := ref.Bom()
+		if bom != nil && bom.Identity() != strings.ToLower(bom.Identity()) {
+			bom = identifier{identity: strings.ToLower(bom.Identity())}
+		}
+		lowered = append(lowered, reference{
+			kind:        kindOf(ref.Kind()),
+			identity:    strings.ToLower(ref.Identity()),
+			bom:         bom,
+			annotations: ref.Annotations(),
+		})
+	}
+	if err := gb.AddReferences(lowered); err != nil {
+		return nil, err
+	}
+	return gb.References(), nil
+}
+
 type identifier struct {
 	identity string
 }
@@ -280,10 +916,46 @@ func (gb identifier) Identity() string {
 	return gb.identity
 }
 
+// gitoidURIPrefix starts the gitoid URI of a blob, gitoid:blob:<algorithm>:<hash>.
+const gitoidURIPrefix = "gitoid:blob:"
+
+// NewIdentifier returns an Identifier for identity, given either as a hex gitoid
+// or as a gitoid URI such as gitoid:blob:sha256:<hash>, which is reduced to its hash.
+// The algorithm of a URI must be sha1 or sha256 and its hash must have the matching length; hex must be lowercase.
 func NewIdentifier(identity string) (Identifier, error) {
-	// TODO check if omnibor matches the format
-	_, err := hex.DecodeString(identity)
-	if err != nil {
+	if strings.HasPrefix(identity, "gitoid:") {
+		hash, err := parseGitoidURI(identity)
+		if err != nil {
+			return nil, err
+		}
+		identity = hash
+	}
+	return newIdentifier(identity)
+}
+
+// parseGitoidURI returns the hash of the gitoid URI of a blob.
+func parseGitoidURI(uri string) (string, error) {
+	rest := strings.TrimPrefix(uri, gitoidURIPrefix)
+	if rest == uri {
+		return "", fmt.Errorf("%w: %q is not a blob", ErrMalformedURI, uri)
+	}
+	algo, hash, ok := strings.Cut(rest, ":")
+	if !ok {
+		return "", fmt.Errorf("%w: %q has no hash", ErrMalformedURI, uri)
+	}
+	length := HashAlgorithm(algo).HexLength()
+	if length == 0 {
+		return "", fmt.Errorf("%w: %q in %q", ErrUnknownAlgorithm, algo, uri)
+	}
+	if len(hash) != length {
+		return "", fmt.Errorf("%w: %d digits of %s in %q", ErrInvalidHashLength, len(hash), algo, uri)
+	}
+	return hash, nil
+}
+
+// newIdentifier is NewIdentifier for a hex gitoid, as found in a document.
+func newIdentifier(identity string) (Identifier, error) {
+	if err := validateHex(identity); err != nil {
 		return nil, err
 	}
 	return &identifier{
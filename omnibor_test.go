@@ -3,14 +3,21 @@ package omnibor
 import (
 	"bytes"
 	"encoding/binary"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"github.com/edwarnicke/gitoid"
+	"io"
+	"math/rand"
+	"strings"
+	"sync"
 	"testing"
+	"testing/iotest"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-// TODO create sha256 version
 func TestFlatWorkflowSha1(t *testing.T) {
 	string1 := "hello"
 	string2 := "world"
@@ -49,7 +56,6 @@ func TestFlatWorkflowSha256(t *testing.T) {
 	assert.Equal(t, "e32e7e7761709be17ef573556a82960d489ddf0092424f7db1c91d8363dde822", ref)
 }
 
-// TODO add sha256
 func TestNestedWorkflowSha1(t *testing.T) {
 	string1 := "hello"
 	string2 := "world"
@@ -92,6 +98,50 @@ func TestNestedWorkflowSha1(t *testing.T) {
 	assert.Equal(t, expected, gb2.String())
 }
 
+func TestNestedWorkflowSha256(t *testing.T) {
+	string1 := "hello"
+	string2 := "world"
+
+	gb := NewSha256OmniBOR()
+	err := gb.AddReferenceFromReader(bytes.NewBufferString(string1), nil, int64(len(string1)))
+	assert.NoError(t, err)
+	err = gb.AddReferenceFromReader(bytes.NewBufferString(string2), nil, int64(len(string2)))
+	assert.NoError(t, err)
+	expected := "blob 8aec4e4876f854f688d0ebfc8f37598f38e5fd6903cccc850ca36591175aeb60\nblob 8df3dab4ddfa6eb2a34065cda27d95af2709d4d2658e1b5fbd145822acf42b28\n"
+
+	assert.Equal(t, expected, gb.String())
+
+	ref := gb.Identity()
+	expected = "e32e7e7761709be17ef573556a82960d489ddf0092424f7db1c91d8363dde822"
+
+	assert.Equal(t, expected, ref)
+
+	string3 := "hello2"
+	string4 := "independent"
+	string5 := "opaque"
+
+	gb2 := NewSha256OmniBOR()
+
+	err = gb2.AddReference([]byte(string3), gb)
+	assert.NoError(t, err)
+
+	err = gb2.AddReference([]byte(string4), nil)
+	assert.NoError(t, err)
+	expected = "blob 1861fbb8d1e47ae6328232968bac77acfd7c9afa2f179afbcdae3fd1b0658a60 bom e32e7e7761709be17ef573556a82960d489ddf0092424f7db1c91d8363dde822\nblob 539ecff67045bafbd8239c900704c28e66c8591058ff7e046e723b849055f97c\n"
+
+	assert.Equal(t, expected, gb2.String())
+	assert.Equal(t, "244dcfd75a60be54d8b4e51a8f9dbb9660bfec564ce4523f5b1db409d7d362f2", gb2.Identity())
+
+	identifier, err := NewIdentifier("a87d2b20b13568a5530ec6a59dacfdda8ee3cd1e3d63c9d13da26d27e3447812")
+	assert.NoError(t, err)
+	err = gb2.AddReference([]byte(string5), identifier)
+	assert.NoError(t, err)
+	expected = "blob 1861fbb8d1e47ae6328232968bac77acfd7c9afa2f179afbcdae3fd1b0658a60 bom e32e7e7761709be17ef573556a82960d489ddf0092424f7db1c91d8363dde822\nblob 539ecff67045bafbd8239c900704c28e66c8591058ff7e046e723b849055f97c\nblob dcf17826ff7a346e6b09704314fb5ef4c9fcceb85c2936b45cab13bc7167991a bom a87d2b20b13568a5530ec6a59dacfdda8ee3cd1e3d63c9d13da26d27e3447812\n"
+
+	assert.Equal(t, expected, gb2.String())
+	assert.Equal(t, "161e0a0be5ece5a994de7ab227f9b0c648d22294d8a7153e4b9558b802f0e31f", gb2.Identity())
+}
+
 func TestMixedNestedWorkflow(t *testing.T) {
 	string1 := "hello"
 	string2 := "world"
@@ -236,6 +286,412 @@ func TestAddExistingMalformedSha256(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestReferencesSince(t *testing.T) {
+	gb := NewSha1OmniBOR()
+	assert.NoError(t, gb.AddReference([]byte("hello"), nil))
+	assert.NoError(t, gb.AddReference([]byte("world"), nil))
+
+	refs, generation := gb.ReferencesSince(0)
+	assert.Len(t, refs, 2)
+	assert.Equal(t, uint64(2), generation)
+
+	assert.NoError(t, gb.AddReference([]byte("independent"), nil))
+	assert.NoError(t, gb.AddReference([]byte("hello2"), nil))
+
+	refs, next := gb.ReferencesSince(generation)
+	assert.Equal(t, uint64(4), next)
+	assert.Len(t, refs, 2)
+	assert.Equal(t, "be78cc5602c5457f144a67e574b8f98b9dc2a1a0", refs[0].Identity())
+	assert.Equal(t, "23294b0610492cf55c1c4835216f20d376a287dd", refs[1].Identity())
+
+	refs, last := gb.ReferencesSince(next)
+	assert.Empty(t, refs)
+	assert.Equal(t, next, last)
+}
+
+func TestReproducibleOrdering(t *testing.T) {
+	bom1, err := NewIdentifier("dc0be356e8c2ba26e66448d97db76ad050206574")
+	assert.NoError(t, err)
+	bom2, err := NewIdentifier("a87d2b20b13568a5530ec6a59dacfdda8ee3cd1e3d63c9d13da26d27e3447812")
+	assert.NoError(t, err)
+
+	type input struct {
+		obj string
+		bom Identifier
+	}
+	inputs := []input{
+		{"hello", nil},
+		{"world", nil},
+		{"hello2", bom1},
+		{"hello2", bom2},
+		{"hello2", nil},
+		{"independent", nil},
+		{"opaque", bom2},
+	}
+
+	build := func(seed int64) ArtifactTree {
+		shuffled := make([]input, len(inputs))
+		copy(shuffled, inputs)
+		rand.New(rand.NewSource(seed)).Shuffle(len(shuffled), func(i, j int) {
+			shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+		})
+
+		gb := NewSha1OmniBOR()
+		wg := &sync.WaitGroup{}
+		for _, in := range shuffled {
+			wg.Add(1)
+			go func(in input) {
+				defer wg.Done()
+				assert.NoError(t, gb.AddReference([]byte(in.obj), in.bom))
+			}(in)
+		}
+		wg.Wait()
+		return gb
+	}
+
+	expected := build(1)
+	for seed := int64(2); seed < 20; seed++ {
+		gb := build(seed)
+		assert.Equal(t, expected.String(), gb.String())
+		assert.Equal(t, expected.Identity(), gb.Identity())
+		assert.Equal(t, referenceStrings(expected.References()), referenceStrings(gb.References()))
+	}
+}
+
+func referenceStrings(refs []Reference) []string {
+	result := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		result = append(result, ref.String())
+	}
+	return result
+}
+
+func TestReferenceOrderingTieBreak(t *testing.T) {
+	bomA := &identifier{identity: "a87d2b20b13568a5530ec6a59dacfdda8ee3cd1e3d63c9d13da26d27e3447812"}
+	bomB := &identifier{identity: "a87d2b20b13568a5530ec6a59dacfdda8ee3cd1e3d63c9d13da26d27e3447813"}
+
+	expected := []Reference{
+		reference{identity: "23294b0610492cf55c1c4835216f20d376a287d0"},
+		reference{identity: "23294b0610492cf55c1c4835216f20d376a287dd"},
+		reference{identity: "23294b0610492cf55c1c4835216f20d376a287dd", bom: bomA},
+		reference{identity: "23294b0610492cf55c1c4835216f20d376a287dd", bom: bomB},
+		reference{identity: "23294b0610492cf55c1c4835216f20d376a287de"},
+	}
+
+	for seed := int64(0); seed < 10; seed++ {
+		refs := make([]Reference, len(expected))
+		copy(refs, expected)
+		rand.New(rand.NewSource(seed)).Shuffle(len(refs), func(i, j int) {
+			refs[i], refs[j] = refs[j], refs[i]
+		})
+
+		by(referenceSorter).sort(refs)
+		assert.Equal(t, referenceStrings(expected), referenceStrings(refs))
+	}
+}
+
+func TestReferencesReturnsCopy(t *testing.T) {
+	gb := NewSha1OmniBOR()
+	assert.NoError(t, gb.AddReference([]byte("hello"), nil))
+	assert.NoError(t, gb.AddReference([]byte("world"), nil))
+	expected := gb.String()
+
+	refs := gb.References()
+	refs[0], refs[1] = refs[1], refs[0]
+
+	assert.Equal(t, expected, gb.String())
+}
+
+func TestAddReferenceFromReaderUnsized(t *testing.T) {
+	pr, pw := io.Pipe()
+	go func() {
+		for _, chunk := range []string{"hel", "lo"} {
+			_, _ = pw.Write([]byte(chunk))
+		}
+		_ = pw.Close()
+	}()
+
+	gb := NewSha1OmniBOR()
+	assert.NoError(t, gb.AddReferenceFromReaderUnsized(pr, nil))
+	assert.Equal(t, "blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\n", gb.String())
+
+	sha256 := NewSha256OmniBOR()
+	assert.NoError(t, sha256.AddReferenceFromReaderUnsized(iotest.OneByteReader(strings.NewReader("hello")), nil))
+	expected := NewSha256OmniBOR()
+	assert.NoError(t, expected.AddReference([]byte("hello"), nil))
+	assert.Equal(t, expected.String(), sha256.String())
+
+	pr, pw = io.Pipe()
+	errBroken := errors.New("broken pipe")
+	go func() {
+		_, _ = pw.Write([]byte("hel"))
+		_ = pw.CloseWithError(errBroken)
+	}()
+	assert.Equal(t, errBroken, gb.AddReferenceFromReaderUnsized(pr, nil))
+	assert.Equal(t, 1, gb.Len())
+}
+
+func TestAddReferenceRange(t *testing.T) {
+	content := "header|hello2|trailer"
+
+	gb := NewSha1OmniBOR()
+	err := gb.AddReferenceRange(strings.NewReader(content), 7, 6, nil)
+	assert.NoError(t, err)
+
+	expected := NewSha1OmniBOR()
+	assert.NoError(t, expected.AddReference([]byte(content[7:13]), nil))
+
+	assert.Equal(t, "blob 23294b0610492cf55c1c4835216f20d376a287dd\n", gb.String())
+	assert.Equal(t, expected.Identity(), gb.Identity())
+
+	err = gb.AddReferenceRange(strings.NewReader(content), 15, 10, nil)
+	assert.Error(t, err)
+
+	err = gb.AddReferenceRange(strings.NewReader(content), -1, 6, nil)
+	assert.Error(t, err)
+}
+
+func TestRemoveReference(t *testing.T) {
+	bom, err := NewIdentifier("dc0be356e8c2ba26e66448d97db76ad050206574")
+	assert.NoError(t, err)
+
+	gb := NewSha1OmniBOR()
+	assert.NoError(t, gb.AddReference([]byte("hello"), nil))
+	assert.NoError(t, gb.AddReference([]byte("hello2"), bom))
+	assert.NoError(t, gb.AddReference([]byte("world"), nil))
+	assert.Equal(t, 3, gb.Len())
+
+	assert.True(t, gb.RemoveReference("23294b0610492cf55c1c4835216f20d376a287dd"))
+	assert.Equal(t, 2, gb.Len())
+	assert.Equal(t, "blob 04fea06420ca60892f73becee3614f6d023a4b7f\n"+
+		"blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\n", gb.String())
+	assert.Equal(t, "dc0be356e8c2ba26e66448d97db76ad050206574", gb.Identity())
+
+	assert.False(t, gb.RemoveReference("23294b0610492cf55c1c4835216f20d376a287dd"))
+	assert.Equal(t, 2, gb.Len())
+
+	assert.True(t, gb.RemoveReference("04fea06420ca60892f73becee3614f6d023a4b7f"))
+	assert.Equal(t, "blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\n", gb.String())
+}
+
+func TestNewOmniBORFromExisting(t *testing.T) {
+	gb, err := NewOmniBORFromExisting(
+		"8df3dab4ddfa6eb2a34065cda27d95af2709d4d2658e1b5fbd145822acf42b28",
+		"8aec4e4876f854f688d0ebfc8f37598f38e5fd6903cccc850ca36591175aeb60",
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "e32e7e7761709be17ef573556a82960d489ddf0092424f7db1c91d8363dde822", gb.Identity())
+
+	gb, err = NewOmniBORFromExisting()
+	assert.NoError(t, err)
+	assert.Equal(t, NewSha1OmniBOR().Identity(), gb.Identity())
+
+	_, err = NewOmniBORFromExisting(
+		"8df3dab4ddfa6eb2a34065cda27d95af2709d4d2658e1b5fbd145822acf42b28",
+		"04fea06420ca60892f73becee3614f6d023a4b7f",
+	)
+	assert.True(t, errors.Is(err, ErrAlgorithmMismatch))
+	assert.Contains(t, err.Error(), "id 1")
+
+	_, err = NewOmniBORFromExisting("04fea0")
+	assert.True(t, errors.Is(err, ErrInvalidHashLength))
+}
+
+func TestAddReferences(t *testing.T) {
+	bom, err := NewIdentifier("dc0be356e8c2ba26e66448d97db76ad050206574")
+	assert.NoError(t, err)
+
+	source := NewSha1OmniBOR()
+	assert.NoError(t, source.AddReference([]byte("hello"), nil))
+	assert.NoError(t, source.AddReference([]byte("hello2"), bom))
+
+	gb := NewSha1OmniBOR()
+	assert.NoError(t, gb.AddReference([]byte("world"), nil))
+	refs := append(source.References(), source.References()...)
+	refs = append(refs, reference{identity: "04fea06420ca60892f73becee3614f6d023a4b7f", bom: bom})
+	assert.NoError(t, gb.AddReferences(refs))
+
+	// identical references are added once, the same object linked to a bom next to the plain one
+	assert.Equal(t, "blob 04fea06420ca60892f73becee3614f6d023a4b7f\n"+
+		"blob 04fea06420ca60892f73becee3614f6d023a4b7f bom dc0be356e8c2ba26e66448d97db76ad050206574\n"+
+		"blob 23294b0610492cf55c1c4835216f20d376a287dd bom dc0be356e8c2ba26e66448d97db76ad050206574\n"+
+		"blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\n", gb.String())
+
+	// the references of a tree rebuild it
+	copied := NewSha1OmniBOR()
+	assert.NoError(t, copied.AddReferences(gb.References()))
+	assert.Equal(t, gb.Identity(), copied.Identity())
+
+	// an invalid reference rejects the whole batch
+	err = gb.AddReferences([]Reference{
+		reference{identity: "be78cc5602c5457f144a67e574b8f98b9dc2a1a0"},
+		reference{identity: "8df3dab4ddfa6eb2a34065cda27d95af2709d4d2658e1b5fbd145822acf42b28"},
+	})
+	assert.True(t, errors.Is(err, ErrAlgorithmMismatch))
+	assert.Contains(t, err.Error(), "reference 1")
+	assert.Equal(t, 4, gb.Len())
+}
+
+func TestAddExistingReferences(t *testing.T) {
+	gb := NewSha1OmniBOR()
+	assert.NoError(t, gb.AddExistingReferences([]string{
+		"b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0",
+		"04fea06420ca60892f73becee3614f6d023a4b7f",
+		"b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0",
+	}))
+	assert.Equal(t, 2, gb.Len())
+	assert.Equal(t, "dc0be356e8c2ba26e66448d97db76ad050206574", gb.Identity())
+
+	err := gb.AddExistingReferences([]string{"be78cc5602c5457f144a67e574b8f98b9dc2a1a0", "be78cc"})
+	assert.True(t, errors.Is(err, ErrInvalidHashLength))
+	assert.False(t, gb.Contains("be78cc5602c5457f144a67e574b8f98b9dc2a1a0"))
+}
+
+func TestContains(t *testing.T) {
+	bom, err := NewIdentifier("dc0be356e8c2ba26e66448d97db76ad050206574")
+	assert.NoError(t, err)
+
+	gb := NewSha1OmniBOR()
+	assert.False(t, gb.Contains("b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0"))
+
+	assert.NoError(t, gb.AddReference([]byte("hello"), nil))
+	assert.NoError(t, gb.AddReference([]byte("hello2"), bom))
+	assert.NoError(t, gb.AddExistingReference("04fea06420ca60892f73becee3614f6d023a4b7f"))
+	assert.True(t, gb.Contains("b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0"))
+	assert.True(t, gb.Contains("23294b0610492cf55c1c4835216f20d376a287dd"))
+	assert.True(t, gb.Contains("04fea06420ca60892f73becee3614f6d023a4b7f"))
+	assert.False(t, gb.Contains("be78cc5602c5457f144a67e574b8f98b9dc2a1a0"))
+	assert.False(t, gb.Contains(""))
+
+	// adding an existing reference again is skipped
+	assert.NoError(t, gb.AddExistingReference("04fea06420ca60892f73becee3614f6d023a4b7f"))
+	assert.Equal(t, 3, gb.Len())
+
+	assert.True(t, gb.RemoveReference("23294b0610492cf55c1c4835216f20d376a287dd"))
+	assert.False(t, gb.Contains("23294b0610492cf55c1c4835216f20d376a287dd"))
+
+	clone := gb.Clone()
+	assert.True(t, clone.RemoveReference("b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0"))
+	assert.False(t, clone.Contains("b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0"))
+	assert.True(t, gb.Contains("b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0"))
+}
+
+func TestClone(t *testing.T) {
+	gb := NewSha256OmniBOR()
+	assert.NoError(t, gb.AddReference([]byte("hello"), nil))
+	assert.NoError(t, gb.AddReference([]byte("world"), nil))
+	identity := gb.Identity()
+
+	clone := gb.Clone()
+	assert.Equal(t, identity, clone.Identity())
+
+	assert.NoError(t, clone.AddReference([]byte("hello2"), gb))
+	assert.True(t, clone.RemoveReference(gb.References()[0].Identity()))
+	assert.Equal(t, 2, clone.Len())
+	assert.NotEqual(t, identity, clone.Identity())
+	assert.Len(t, clone.Identity(), 64)
+
+	assert.Equal(t, 2, gb.Len())
+	assert.Equal(t, identity, gb.Identity())
+
+	// the clone continues the generation of the original
+	refs, _ := clone.ReferencesSince(2)
+	assert.Len(t, refs, 1)
+	assert.Equal(t, gb, refs[0].Bom())
+}
+
+func TestWithComparator(t *testing.T) {
+	bom, err := NewIdentifier("dc0be356e8c2ba26e66448d97db76ad050206574")
+	assert.NoError(t, err)
+	linkedFirst := func(r1, r2 Reference) bool {
+		return r1.Bom() != nil && r2.Bom() == nil
+	}
+
+	canonical := NewSha1OmniBOR()
+	gb := NewSha1OmniBOR(WithComparator(linkedFirst))
+	for _, tree := range []ArtifactTree{canonical, gb} {
+		assert.NoError(t, tree.AddReference([]byte("world"), nil))
+		assert.NoError(t, tree.AddReference([]byte("hello"), nil))
+		assert.NoError(t, tree.AddReference([]byte("hello2"), bom))
+	}
+
+	expected := []string{
+		"blob 23294b0610492cf55c1c4835216f20d376a287dd bom dc0be356e8c2ba26e66448d97db76ad050206574\n",
+		"blob 04fea06420ca60892f73becee3614f6d023a4b7f\n",
+		"blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\n",
+	}
+	assert.Equal(t, expected, referenceStrings(gb.References()))
+
+	var walked []Reference
+	assert.NoError(t, gb.Walk(func(ref Reference) error {
+		walked = append(walked, ref)
+		return nil
+	}))
+	assert.Equal(t, expected, referenceStrings(walked))
+
+	assert.Equal(t, canonical.String(), gb.String())
+	assert.Equal(t, canonical.Identity(), gb.Identity())
+	assert.Equal(t, canonical.CanonicalJSON(), gb.CanonicalJSON())
+	assert.Equal(t, expected, referenceStrings(gb.Clone().References()))
+
+	insertion := NewSha256OmniBOR(WithComparator(func(r1, r2 Reference) bool {
+		return false
+	}))
+	assert.NoError(t, insertion.AddReference([]byte("world"), nil))
+	assert.NoError(t, insertion.AddReference([]byte("hello"), nil))
+	refs := insertion.References()
+	assert.Equal(t, "8aec4e4876f854f688d0ebfc8f37598f38e5fd6903cccc850ca36591175aeb60", refs[1].Identity())
+}
+
+func TestWalk(t *testing.T) {
+	gb := NewSha1OmniBOR()
+	assert.NoError(t, gb.AddReference([]byte("world"), nil))
+	assert.NoError(t, gb.AddReference([]byte("hello"), nil))
+	assert.NoError(t, gb.AddReference([]byte("hello2"), nil))
+
+	var sum uint64
+	var lines strings.Builder
+	err := gb.Walk(func(ref Reference) error {
+		b, err := hex.DecodeString(ref.Identity()[:8])
+		if err != nil {
+			return err
+		}
+		sum += uint64(binary.BigEndian.Uint32(b))
+		lines.WriteString(ref.String())
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0x04fea064+0x23294b06+0xb6fc4c62), sum)
+	assert.Equal(t, gb.String(), lines.String())
+
+	// sorting in place must not lose the insertion order
+	refs, _ := gb.ReferencesSince(0)
+	assert.Equal(t, "04fea06420ca60892f73becee3614f6d023a4b7f", refs[0].Identity())
+	assert.Equal(t, "b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0", refs[1].Identity())
+}
+
+func TestWalkAbort(t *testing.T) {
+	gb := NewSha1OmniBOR()
+	assert.NoError(t, gb.AddReference([]byte("hello"), nil))
+	assert.NoError(t, gb.AddReference([]byte("hello2"), nil))
+	assert.NoError(t, gb.AddReference([]byte("world"), nil))
+
+	errStop := errors.New("stop")
+	var visited []string
+	err := gb.Walk(func(ref Reference) error {
+		visited = append(visited, ref.Identity())
+		if len(visited) == 2 {
+			return errStop
+		}
+		return nil
+	})
+	assert.Equal(t, errStop, err)
+	assert.Equal(t, []string{
+		"04fea06420ca60892f73becee3614f6d023a4b7f",
+		"23294b0610492cf55c1c4835216f20d376a287dd",
+	}, visited)
+}
+
 func BenchmarkNewOmniBOR(b *testing.B) {
 	dataset := generateDataset(b.N)
 
@@ -251,6 +707,32 @@ func BenchmarkNewOmniBOR(b *testing.B) {
 	fmt.Println(len(gb.References()), len(dataset), b.N)
 }
 
+func generateIdentities(n int) []string {
+	ids := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		ids = append(ids, fmt.Sprintf("%040x", i))
+	}
+	return ids
+}
+
+func BenchmarkAddExistingReference(b *testing.B) {
+	ids := generateIdentities(b.N)
+	gb := NewSha1OmniBOR()
+
+	b.ResetTimer()
+	for _, id := range ids {
+		_ = gb.AddExistingReference(id)
+	}
+}
+
+func BenchmarkAddExistingReferences(b *testing.B) {
+	ids := generateIdentities(b.N)
+	gb := NewSha1OmniBOR()
+
+	b.ResetTimer()
+	_ = gb.AddExistingReferences(ids)
+}
+
 func generateDataset(n int) [][]byte {
 	dataset := make([][]byte, 0)
 	for i := 0; i < n; i++ {
@@ -264,3 +746,406 @@ func generateDataset(n int) [][]byte {
 	}
 	return dataset
 }
+
+func TestIsEmpty(t *testing.T) {
+	for gb, identity := range map[ArtifactTree]string{
+		NewSha1OmniBOR():   EmptySha1Identity,
+		NewSha256OmniBOR(): EmptySha256Identity,
+	} {
+		assert.True(t, gb.IsEmpty())
+		assert.Equal(t, "", gb.String())
+		assert.Equal(t, identity, gb.Identity())
+
+		assert.NoError(t, gb.AddReference([]byte("hello"), nil))
+		assert.False(t, gb.IsEmpty())
+		assert.NotEqual(t, identity, gb.Identity())
+	}
+
+	gb := NewSha1OmniBOR()
+	assert.NoError(t, gb.AddReference([]byte("hello"), nil))
+	assert.True(t, gb.RemoveReference("b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0"))
+	assert.True(t, gb.IsEmpty())
+	assert.Equal(t, EmptySha1Identity, gb.Identity())
+}
+
+func TestQualifiedIdentity(t *testing.T) {
+	gb := NewSha1OmniBOR()
+	assert.NoError(t, gb.AddReference([]byte("hello"), nil))
+	assert.NoError(t, gb.AddExistingReference("04fea06420ca60892f73becee3614f6d023a4b7f"))
+	refs := gb.References()
+	assert.Equal(t, "sha1:04fea06420ca60892f73becee3614f6d023a4b7f", refs[0].QualifiedIdentity())
+	assert.Equal(t, "sha1:b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0", refs[1].QualifiedIdentity())
+	assert.Equal(t, "b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0", refs[1].Identity())
+
+	gb = NewSha256OmniBOR()
+	assert.NoError(t, gb.AddReferences([]Reference{reference{identity: "8aec4e4876f854f688d0ebfc8f37598f38e5fd6903cccc850ca36591175aeb60"}}))
+	assert.Equal(t, "sha256:8aec4e4876f854f688d0ebfc8f37598f38e5fd6903cccc850ca36591175aeb60", gb.References()[0].QualifiedIdentity())
+
+	parsed, err := Parse(strings.NewReader("blob 8df3dab4ddfa6eb2a34065cda27d95af2709d4d2658e1b5fbd145822acf42b28\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "sha256:8df3dab4ddfa6eb2a34065cda27d95af2709d4d2658e1b5fbd145822acf42b28", parsed.References()[0].QualifiedIdentity())
+}
+
+func TestNewReference(t *testing.T) {
+	bom, err := NewIdentifier("dc0be356e8c2ba26e66448d97db76ad050206574")
+	require.NoError(t, err)
+
+	ref, err := NewReference("23294b0610492cf55c1c4835216f20d376a287dd", bom)
+	require.NoError(t, err)
+	assert.Equal(t, "blob 23294b0610492cf55c1c4835216f20d376a287dd bom dc0be356e8c2ba26e66448d97db76ad050206574\n", ref.String())
+	assert.Equal(t, "sha1:23294b0610492cf55c1c4835216f20d376a287dd", ref.QualifiedIdentity())
+	assert.Equal(t, bom, ref.Bom())
+
+	// a sha256 object may link to a sha1 tree
+	ref, err = NewReference("8aec4e4876f854f688d0ebfc8f37598f38e5fd6903cccc850ca36591175aeb60", bom)
+	require.NoError(t, err)
+	assert.Equal(t, "sha256:8aec4e4876f854f688d0ebfc8f37598f38e5fd6903cccc850ca36591175aeb60", ref.QualifiedIdentity())
+
+	ref, err = NewReference("b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\n", ref.String())
+	assert.Nil(t, ref.Bom())
+
+	gb := NewSha1OmniBOR()
+	assert.NoError(t, gb.AddReferences([]Reference{ref}))
+	assert.Equal(t, ref.String(), gb.String())
+
+	_, err = NewReference("b6fc4c", nil)
+	assert.True(t, errors.Is(err, ErrInvalidHashLength))
+	_, err = NewReference("B6FC4C620B67D95F953A5C1C1230AAAB5DB5A1B0", nil)
+	assert.True(t, errors.Is(err, ErrInvalidHex))
+	_, err = NewReference("z6fc4c620b67d95f953a5c1c1230aaab5db5a1b0", nil)
+	assert.True(t, errors.Is(err, ErrInvalidHex))
+	_, err = NewReference("b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0", identifier{identity: "dc0be3"})
+	assert.True(t, errors.Is(err, ErrInvalidHashLength))
+}
+
+func TestLinkedReferences(t *testing.T) {
+	child := NewSha1OmniBOR()
+	assert.NoError(t, child.AddReference([]byte("hello"), nil))
+	other, err := NewIdentifier("dc0be356e8c2ba26e66448d97db76ad050206574")
+	require.NoError(t, err)
+
+	gb := NewSha1OmniBOR()
+	assert.NoError(t, gb.AddReference([]byte("world"), nil))
+	assert.NoError(t, gb.AddReference([]byte("independent"), other))
+	assert.NoError(t, gb.AddReference([]byte("hello"), nil))
+	assert.NoError(t, gb.AddReference([]byte("hello2"), child))
+
+	linked := gb.LinkedReferences()
+	require.Len(t, linked, 2)
+	assert.Equal(t, "blob 23294b0610492cf55c1c4835216f20d376a287dd bom "+child.Identity()+"\n", linked[0].String())
+	assert.Equal(t, "blob be78cc5602c5457f144a67e574b8f98b9dc2a1a0 bom dc0be356e8c2ba26e66448d97db76ad050206574\n", linked[1].String())
+
+	assert.Empty(t, child.LinkedReferences())
+}
+
+func TestAddReferenceDuplicate(t *testing.T) {
+	gb := NewSha1OmniBOR()
+	require.NoError(t, gb.AddReference([]byte("hello"), nil))
+	require.NoError(t, gb.AddReferenceFromReader(strings.NewReader("hello"), nil, 5))
+	assert.Equal(t, "blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\n", gb.String())
+}
+
+func TestIdentities(t *testing.T) {
+	child := NewSha1OmniBOR()
+	assert.NoError(t, child.AddReference([]byte("hello"), nil))
+
+	gb := NewSha1OmniBOR()
+	assert.NoError(t, gb.AddReference([]byte("world"), nil))
+	assert.NoError(t, gb.AddReference([]byte("hello"), child))
+	assert.NoError(t, gb.AddReference([]byte("hello2"), nil))
+	assert.NoError(t, gb.AddReference([]byte("hello"), nil))
+
+	identities := gb.Identities()
+	assert.Equal(t, []string{
+		"04fea06420ca60892f73becee3614f6d023a4b7f",
+		"23294b0610492cf55c1c4835216f20d376a287dd",
+		"b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0",
+	}, identities)
+
+	var fromRefs []string
+	for _, ref := range gb.References() {
+		if len(fromRefs) == 0 || fromRefs[len(fromRefs)-1] != ref.Identity() {
+			fromRefs = append(fromRefs, ref.Identity())
+		}
+	}
+	assert.Equal(t, fromRefs, identities)
+
+	assert.Empty(t, NewSha1OmniBOR().Identities())
+}
+
+func TestAddReferenceFromReaderID(t *testing.T) {
+	for _, gb := range []ArtifactTree{NewSha1OmniBOR(), NewSha256OmniBOR()} {
+		options := []gitoid.Option{gitoid.WithContentLength(6)}
+		if len(gb.Identity()) == 64 {
+			options = append(options, gitoid.WithSha256())
+		}
+		expected, err := gitoid.New(strings.NewReader("hello2"), options...)
+		require.NoError(t, err)
+
+		id, err := gb.AddReferenceFromReaderID(strings.NewReader("hello2"), nil, 6)
+		require.NoError(t, err)
+		assert.Equal(t, expected.String(), id)
+		assert.True(t, gb.Contains(id))
+
+		id, err = gb.AddReferenceFromReaderID(strings.NewReader("hello2"), nil, 7)
+		assert.True(t, errors.Is(err, ErrShortRead))
+		assert.Equal(t, "", id)
+		assert.Equal(t, 1, gb.Len())
+	}
+}
+
+func TestSortIgnoresAlgorithmLabel(t *testing.T) {
+	ids := []string{
+		"b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0",
+		"04fea06420ca60892f73becee3614f6d023a4b7f",
+		"23294b0610492cf55c1c4835216f20d376a287dd",
+	}
+	sorted := func(labels ...HashAlgorithm) []string {
+		refs := make([]Reference, 0, len(ids))
+		for i, id := range ids {
+			refs = append(refs, reference{hashType: labels[i], identity: id})
+		}
+		by(referenceSorter).sort(refs)
+		result := make([]string, 0, len(refs))
+		for _, ref := range refs {
+			result = append(result, ref.Identity())
+		}
+		return result
+	}
+
+	expected := []string{ids[1], ids[2], ids[0]}
+	assert.Equal(t, expected, sorted(Sha1, Sha1, Sha1))
+	assert.Equal(t, expected, sorted(Sha256, Sha1, "sha1+sha256"))
+	assert.Equal(t, expected, sorted("", "z", Sha256))
+}
+
+func TestGet(t *testing.T) {
+	bom, err := NewIdentifier("dc0be356e8c2ba26e66448d97db76ad050206574")
+	require.NoError(t, err)
+
+	gb, err := Parse(strings.NewReader("blob 04fea06420ca60892f73becee3614f6d023a4b7f\n" +
+		"blob 23294b0610492cf55c1c4835216f20d376a287dd bom dc0be356e8c2ba26e66448d97db76ad050206574\n"))
+	require.NoError(t, err)
+
+	ref, ok := gb.Get("23294b0610492cf55c1c4835216f20d376a287dd")
+	require.True(t, ok)
+	assert.Equal(t, bom.Identity(), ref.Bom().Identity())
+
+	ref, ok = gb.Get("04fea06420ca60892f73becee3614f6d023a4b7f")
+	require.True(t, ok)
+	assert.Nil(t, ref.Bom())
+
+	ref, ok = gb.Get("b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0")
+	assert.False(t, ok)
+	assert.Nil(t, ref)
+
+	// hashing the same content again linked elsewhere keeps both, the unlinked one renders first
+	assert.NoError(t, gb.AddReference([]byte("hello2"), nil))
+	ref, ok = gb.Get("23294b0610492cf55c1c4835216f20d376a287dd")
+	require.True(t, ok)
+	assert.Nil(t, ref.Bom())
+
+	clone := gb.Clone()
+	assert.True(t, gb.RemoveReference("23294b0610492cf55c1c4835216f20d376a287dd"))
+	_, ok = gb.Get("23294b0610492cf55c1c4835216f20d376a287dd")
+	assert.False(t, ok)
+	_, ok = clone.Get("23294b0610492cf55c1c4835216f20d376a287dd")
+	assert.True(t, ok)
+}
+
+func TestNewIdentifierURI(t *testing.T) {
+	const sha1 = "dc0be356e8c2ba26e66448d97db76ad050206574"
+	const sha256 = "e32e7e7761709be17ef573556a82960d489ddf0092424f7db1c91d8363dde822"
+
+	for _, input := range []string{sha1, "gitoid:blob:sha1:" + sha1} {
+		identifier, err := NewIdentifier(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, sha1, identifier.Identity())
+	}
+	for _, input := range []string{sha256, "gitoid:blob:sha256:" + sha256} {
+		identifier, err := NewIdentifier(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, sha256, identifier.Identity())
+	}
+
+	malformed := map[string]error{
+		"gitoid:tree:sha1:" + sha1:            ErrMalformedURI,
+		"gitoid:blob:" + sha1:                 ErrMalformedURI,
+		"gitoid:blob:md5:" + sha1:             ErrUnknownAlgorithm,
+		"gitoid:blob:sha256:" + sha1:          ErrInvalidHashLength,
+		"gitoid:blob:sha1:" + sha256:          ErrInvalidHashLength,
+		"gitoid:blob:sha1:" + sha1[:39] + "g": ErrInvalidHex,
+	}
+	for input, expected := range malformed {
+		_, err := NewIdentifier(input)
+		assert.ErrorIs(t, err, expected, input)
+	}
+
+	// documents keep holding bare hex
+	_, err := Parse(strings.NewReader("blob be78cc5602c5457f144a67e574b8f98b9dc2a1a0 bom gitoid:blob:sha1:" + sha1 + "\n"))
+	assert.Error(t, err)
+}
+
+func TestReset(t *testing.T) {
+	bom, err := NewIdentifier("dc0be356e8c2ba26e66448d97db76ad050206574")
+	require.NoError(t, err)
+
+	for _, newTree := range []func(...TreeOption) ArtifactTree{NewSha1OmniBOR, NewSha256OmniBOR} {
+		gb := newTree()
+		require.NoError(t, gb.AddReference([]byte("hello"), bom))
+		require.NoError(t, gb.AddReference([]byte("world"), nil))
+		_, generation := gb.ReferencesSince(0)
+
+		gb.Reset()
+		fresh := newTree()
+		assert.True(t, gb.IsEmpty())
+		assert.Equal(t, fresh.Identity(), gb.Identity())
+		assert.Equal(t, fresh.String(), gb.String())
+		assert.False(t, gb.Contains(fresh.Identity()))
+
+		require.NoError(t, gb.AddReference([]byte("hello"), nil))
+		require.NoError(t, fresh.AddReference([]byte("hello"), nil))
+		assert.Equal(t, fresh.Identity(), gb.Identity())
+		assert.Equal(t, fresh.String(), gb.String())
+
+		added, _ := gb.ReferencesSince(generation)
+		assert.Len(t, added, 1)
+	}
+}
+
+func BenchmarkReset(b *testing.B) {
+	dataset := generateIdentities(100)
+
+	b.Run("new", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			gb := NewSha1OmniBOR()
+			_ = gb.AddExistingReferences(dataset)
+		}
+	})
+	b.Run("reset", func(b *testing.B) {
+		b.ReportAllocs()
+		gb := NewSha1OmniBOR()
+		for i := 0; i < b.N; i++ {
+			gb.Reset()
+			_ = gb.AddExistingReferences(dataset)
+		}
+	})
+}
+
+func TestIdentityKeepsInsertionOrder(t *testing.T) {
+	gb := NewSha1OmniBOR()
+	for _, content := range []string{"world", "independent", "hello"} {
+		require.NoError(t, gb.AddReference([]byte(content), nil))
+	}
+	insertionOrder := func() []string {
+		refs, _ := gb.ReferencesSince(0)
+		identities := make([]string, 0, len(refs))
+		for _, ref := range refs {
+			identities = append(identities, ref.Identity())
+		}
+		return identities
+	}
+	expected := []string{
+		"04fea06420ca60892f73becee3614f6d023a4b7f",
+		"be78cc5602c5457f144a67e574b8f98b9dc2a1a0",
+		"b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0",
+	}
+
+	_ = gb.Identity()
+	_ = gb.String()
+	_ = gb.References()
+	_ = gb.Walk(func(Reference) error { return nil })
+	_, _ = gb.Iterator(2).Next()
+	assert.Equal(t, expected, insertionOrder())
+
+	internal := make([]string, 0, len(expected))
+	for _, ref := range gb.(*omniBor).gitRefs {
+		internal = append(internal, ref.Identity())
+	}
+	assert.Equal(t, expected, internal)
+
+	// a comparator keeping insertion order sees it whatever was called before
+	ordered := NewSha1OmniBOR(WithComparator(func(r1, r2 Reference) bool { return false }))
+	for _, content := range []string{"world", "independent", "hello"} {
+		require.NoError(t, ordered.AddReference([]byte(content), nil))
+	}
+	identities := func() []string {
+		result := make([]string, 0, len(expected))
+		for _, ref := range ordered.References() {
+			result = append(result, ref.Identity())
+		}
+		return result
+	}
+	assert.Equal(t, expected, identities())
+	_ = ordered.Identity()
+	_ = ordered.Walk(func(Reference) error { return nil })
+	assert.Equal(t, expected, identities())
+	_, _ = ordered.Iterator(2).Next()
+	assert.Equal(t, expected, identities())
+}
+
+func TestReferenceGitOID(t *testing.T) {
+	for _, gb := range []ArtifactTree{NewSha1OmniBOR(), NewSha256OmniBOR()} {
+		require.NoError(t, gb.AddReference([]byte("hello"), nil))
+		ref := gb.References()[0]
+
+		gitOID := ref.GitOID()
+		assert.Equal(t, ref.Identity(), gitOID.String())
+		assert.Equal(t, "gitoid:blob:"+ref.QualifiedIdentity(), gitOID.URI())
+		assert.Equal(t, ref.Identity(), hex.EncodeToString(gitOID.Bytes()))
+
+		opts := []gitoid.Option{gitoid.WithContentLength(5)}
+		if len(ref.Identity()) == 64 {
+			opts = append(opts, gitoid.WithSha256())
+		}
+		hashed, err := gitoid.New(strings.NewReader("hello"), opts...)
+		require.NoError(t, err)
+		assert.True(t, hashed.Equal(gitOID))
+	}
+}
+
+func TestNormalizeReferences(t *testing.T) {
+	plain, err := NewReference("b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0", nil)
+	require.NoError(t, err)
+	refs := []Reference{
+		reference{identity: "B6FC4C620B67D95F953A5C1C1230AAAB5DB5A1B0", bom: identifier{identity: "DC0BE356E8C2BA26E66448D97DB76AD050206574"}},
+		reference{identity: "23294b0610492cf55c1c4835216f20d376a287dd"},
+		plain,
+		reference{identity: "04fea06420ca60892f73becee3614f6d023a4b7f"},
+		reference{identity: "b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0", bom: identifier{identity: "dc0be356e8c2ba26e66448d97db76ad050206574"}},
+	}
+
+	normalized, err := NormalizeReferences(refs, Sha1)
+	require.NoError(t, err)
+	var lines []string
+	for _, ref := range normalized {
+		lines = append(lines, ref.String())
+	}
+	// sorted, lowercased and deduplicated, the object is kept once per bom
+	assert.Equal(t, []string{
+		"blob 04fea06420ca60892f73becee3614f6d023a4b7f\n",
+		"blob 23294b0610492cf55c1c4835216f20d376a287dd\n",
+		"blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\n",
+		"blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0 bom dc0be356e8c2ba26e66448d97db76ad050206574\n",
+	}, lines)
+
+	_, err = NormalizeReferences(refs, Sha256)
+	assert.True(t, errors.Is(err, ErrAlgorithmMismatch))
+
+	_, err = NormalizeReferences(append(refs, reference{identity: "b6fc4c"}), Sha1)
+	assert.True(t, errors.Is(err, ErrInvalidHashLength))
+	assert.Contains(t, err.Error(), "reference 5")
+
+	_, err = NormalizeReferences([]Reference{reference{identity: "zzfc4c620b67d95f953a5c1c1230aaab5db5a1b0"}}, Sha1)
+	assert.True(t, errors.Is(err, ErrInvalidHex))
+
+	_, err = NormalizeReferences(refs, "md5")
+	assert.True(t, errors.Is(err, ErrUnknownAlgorithm))
+
+	normalized, err = NormalizeReferences(nil, Sha1)
+	assert.NoError(t, err)
+	assert.Empty(t, normalized)
+}
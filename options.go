@@ -0,0 +1,219 @@
+package omnibor
+
+import (
+	"fmt"
+	"hash"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+)
+
+// Option configures how content is ingested into an ArtifactTree.
+type Option func(*options)
+
+type options struct {
+	workers        int
+	sharedHasher   *HasherPool
+	excludes       []string
+	followSymlinks bool
+	progress       func(path string, done, total int)
+	hashCache      HashCache
+	metrics        *Metrics
+	fileIdentities func(path, identity string)
+	buffers        *sync.Pool
+
+	continueOnError bool
+	skippedLock     sync.Mutex
+	skipped         FileErrors
+}
+
+func newOptions(opts ...Option) *options {
+	o := &options{
+		workers:        defaultWorkers(),
+		followSymlinks: true,
+		buffers:        copyBuffers,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// ingestOptions returns the options of an ingestion into srv, the tree's defaults overridden by opts.
+func (srv *omniBor) ingestOptions(opts []Option) *options {
+	all := make([]Option, 0, len(srv.defaults)+len(opts))
+	all = append(all, srv.defaults...)
+	return newOptions(append(all, opts...)...)
+}
+
+// defaultWorkers returns the smaller of GOMAXPROCS and the number of CPUs.
+func defaultWorkers() int {
+	if runtime.GOMAXPROCS(0) < runtime.NumCPU() {
+		return runtime.GOMAXPROCS(0)
+	}
+	return runtime.NumCPU()
+}
+
+// WithWorkers sets the number of files hashed concurrently by AddTree.
+// Values below one are ignored.
+func WithWorkers(n int) Option {
+	return func(o *options) {
+		if n >= 1 {
+			o.workers = n
+		}
+	}
+}
+
+// WithBufferSize sets the size in bytes of the buffer AddTree, AddFS, AddTar and AddZip copy file content
+// into the hasher with, 32 KiB by default. A larger buffer, such as 1 MiB, means fewer reads and improves throughput
+// on multi-gigabyte files read from fast storage, at the cost of a buffer per worker. Values below one are ignored.
+func WithBufferSize(n int) Option {
+	return func(o *options) {
+		if n >= 1 {
+			o.buffers = newBufferPool(n)
+		}
+	}
+}
+
+// WithSharedHasher routes hashing through pool so that concurrent hashing is bounded across
+// every tree using the same pool rather than per tree.
+func WithSharedHasher(pool *HasherPool) Option {
+	return func(o *options) {
+		o.sharedHasher = pool
+	}
+}
+
+// WithExclude makes AddTree skip every file and directory matching one of the glob patterns,
+// using the syntax of filepath.Match. A pattern is matched against the path relative to the walked root
+// and against the base name, so "node_modules" skips that directory at any depth while "build/*.o" only
+// applies below the top level build directory. Excluded directories are not descended into.
+// Repeated options add up.
+func WithExclude(patterns ...string) Option {
+	return func(o *options) {
+		o.excludes = append(o.excludes, patterns...)
+	}
+}
+
+// WithFollowSymlinks sets whether AddTree follows symbolic links, which it does by default.
+// A followed link is referenced by its target's content and a linked directory is walked like any other,
+// though never twice, so symlink cycles terminate.
+// A link that is not followed is referenced like git records it, as a blob holding the link's target path,
+// so links pointing outside the tree or into a cycle are never resolved.
+func WithFollowSymlinks(follow bool) Option {
+	return func(o *options) {
+		o.followSymlinks = follow
+	}
+}
+
+// WithProgress makes AddTree call fn after every file it added, with the file's path, the number of files added
+// so far and the number of files found so far. The walk and the hashing run concurrently, so total keeps growing
+// until the walk has finished. Calls are serialized but come from the hashing goroutines, fn should return quickly.
+func WithProgress(fn func(path string, done, total int)) Option {
+	return func(o *options) {
+		o.progress = fn
+	}
+}
+
+// WithFileIdentities makes AddTree and AddFS call fn with the path and gitoid of every file they add, for tools
+// mapping paths to gitoids alongside the document, which records no paths. The path is the one the walk reached
+// the file by, below the walked root, even when it is a followed symbolic link. Calls are serialized like those of
+// WithProgress.
+func WithFileIdentities(fn func(path, identity string)) Option {
+	return func(o *options) {
+		o.fileIdentities = fn
+	}
+}
+
+// WithContinueOnError sets whether AddTree, AddFS and AddZip skip files and directories that cannot be read
+// rather than stopping at the first one, which they do by default. When continuing, the readable files are added
+// and the errors of the skipped ones are returned together as FileErrors once the walk has finished.
+func WithContinueOnError(continueOnError bool) Option {
+	return func(o *options) {
+		o.continueOnError = continueOnError
+	}
+}
+
+// skip records err and reports true if the ingestion continues past errors, see WithContinueOnError.
+func (o *options) skip(err error) bool {
+	if !o.continueOnError {
+		return false
+	}
+	o.skippedLock.Lock()
+	defer o.skippedLock.Unlock()
+	o.skipped = append(o.skipped, err)
+	return true
+}
+
+// HashCache remembers the gitoids of files so that AddTree can skip hashing files that did not change.
+// Implementations must be safe for concurrent use.
+type HashCache interface {
+	// Lookup returns the gitoid recorded for the file at path, provided info shows the file unchanged since.
+	Lookup(path string, info os.FileInfo) (string, bool)
+
+	// Record remembers that the file at path, described by info, has the given gitoid.
+	Record(path string, info os.FileInfo, identity string)
+}
+
+// WithHashCache makes AddTree add the gitoid cache holds for a file rather than reading it, and record the gitoid
+// of every file it hashes. A cached gitoid of another hash algorithm than the tree's is ignored.
+// Trusting the cache is only as safe as its notion of a file being unchanged, typically its size and modification time.
+// AddFS does not consult the cache.
+func WithHashCache(cache HashCache) Option {
+	return func(o *options) {
+		o.hashCache = cache
+	}
+}
+
+// excluded reports whether path, found while walking root, matches one of the exclude patterns.
+func (o *options) excluded(root, path string) (bool, error) {
+	if len(o.excludes) == 0 {
+		return false, nil
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false, err
+	}
+	if rel == "." {
+		return false, nil
+	}
+	for _, pattern := range o.excludes {
+		for _, name := range []string{rel, filepath.Base(rel)} {
+			if matched, err := filepath.Match(pattern, name); err != nil || matched {
+				return matched, err
+			}
+		}
+	}
+	return false, nil
+}
+
+// TreeOption configures an ArtifactTree when it is constructed.
+type TreeOption func(*omniBor)
+
+// WithComparator makes References and Walk return references ordered by less,
+// for example to group linked references or to keep insertion order while debugging.
+// Ties keep insertion order, so a comparator always returning false lists references in the order they were added.
+// String, SectionedString, CanonicalJSON and Identity always use the canonical ascending order required by the spec.
+func WithComparator(less func(r1, r2 Reference) bool) TreeOption {
+	return func(srv *omniBor) {
+		srv.comparator = less
+	}
+}
+
+// WithHashFunc makes the tree hash objects and its document with hashers returned by newHash instead of those of
+// crypto/sha1 or crypto/sha256, for example a FIPS-validated implementation linked from elsewhere.
+// newHash must return digests of the size of the tree's hash algorithm, the constructor panics otherwise.
+// Every identity the tree computes depends on the hash function: one that does not compute the tree's
+// algorithm, such as BLAKE3 for a sha256 tree, yields identities no other OmniBOR tool reproduces.
+func WithHashFunc(newHash func() hash.Hash) TreeOption {
+	return func(srv *omniBor) {
+		if size := newHash().Size(); 2*size != srv.hashType.HexLength() {
+			panic(fmt.Sprintf("omnibor: hash function of %d byte digests for a %s tree", size, srv.hashType))
+		}
+		srv.hashers = &sync.Pool{
+			New: func() interface{} {
+				return newHash()
+			},
+		}
+	}
+}
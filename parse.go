@@ -0,0 +1,172 @@
+package omnibor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Parse reads an OmniBOR document and reconstructs the ArtifactTree it describes.
+// The hash algorithm is inferred from the length of the first reference identity,
+// an empty document yields an empty sha1 tree.
+// Lines starting with `#` are comments and are skipped, see SectionedString.
+//
+// Lines may end in "\n" or "\r\n" and the final line needs no line ending. Empty lines are permitted only
+// at the end of the document. No other whitespace is permitted: fields are separated by exactly one space
+// and leading or trailing spaces and tabs make a line malformed.
+func Parse(r io.Reader) (ArtifactTree, error) {
+	return parse(r, ParseOptions{})
+}
+
+// ParseStrict parses an OmniBOR document like Parse and additionally requires the references in the order
+// String writes them: ascending identity, as the spec mandates, and an identity listed once per bom ordered
+// by line, see referenceSorter. A document whose lines were reordered is rejected with an error wrapping ErrUnsorted,
+// one repeating a line, or an identity with the same bom, with an error wrapping ErrDuplicateIdentity.
+// Parse keeps an object listed once per bom and drops a line repeating an earlier reference exactly.
+func ParseStrict(r io.Reader) (ArtifactTree, error) {
+	return parse(r, ParseOptions{Strict: true})
+}
+
+// ParseOptions limits what ParseWithOptions accepts, for documents coming from untrusted sources.
+type ParseOptions struct {
+	// Strict requires references in the order of String without duplicates, as ParseStrict does.
+	Strict bool
+
+	// MaxReferences is the largest number of references a document may hold, 0 for no limit.
+	// A document with more references is rejected with ErrTooManyReferences as soon as the limit is passed,
+	// without reading the rest of it.
+	MaxReferences int
+}
+
+// ParseWithOptions parses an OmniBOR document like Parse, within the limits set by opts.
+func ParseWithOptions(r io.Reader, opts ParseOptions) (ArtifactTree, error) {
+	return parse(r, opts)
+}
+
+func parse(r io.Reader, opts ParseOptions) (ArtifactTree, error) {
+	var gb *omniBor
+	var previous *reference  // last reference read, for the order checks of opts.Strict
+	var boms map[string]bool // boms the identity of previous was listed with
+	blankLine := 0
+	references := 0
+
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		// bufio.ScanLines drops the line ending, including the "\r" of a "\r\n"
+		line := scanner.Text()
+		if line == "" {
+			if blankLine == 0 {
+				blankLine = lineNo
+			}
+			continue
+		}
+		if blankLine != 0 {
+			return nil, fmt.Errorf("line %d: %w: empty line before the end of the document", blankLine, ErrMalformedReference)
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		ref, err := parseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+
+		references++
+		if opts.MaxReferences > 0 && references > opts.MaxReferences {
+			return nil, fmt.Errorf("line %d: %w: more than %d", lineNo, ErrTooManyReferences, opts.MaxReferences)
+		}
+
+		if opts.Strict {
+			if err := checkOrder(previous, ref, boms); err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			if previous == nil || previous.identity != ref.identity {
+				boms = make(map[string]bool)
+			}
+			boms[bomOf(ref)] = true
+			previous = &ref
+		}
+
+		if gb == nil {
+			if gb, err = newTreeForLength(len(ref.identity)); err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+		}
+
+		if err := gb.addParsedReference(ref); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if gb == nil {
+		return NewSha1OmniBOR(), nil
+	}
+	return gb, nil
+}
+
+// checkOrder checks that ref may follow previous, nil for the first reference, in a document written by String.
+// boms holds the boms the identity of previous was listed with.
+func checkOrder(previous *reference, ref reference, boms map[string]bool) error {
+	if previous == nil {
+		return nil
+	}
+	if ref.identity == previous.identity && boms[bomOf(ref)] {
+		return fmt.Errorf("%w: %s", ErrDuplicateIdentity, strings.TrimSuffix(ref.String(), "\n"))
+	}
+	if referenceSorter(ref, *previous) {
+		return fmt.Errorf("%w: %s follows %s", ErrUnsorted, ref.identity, previous.identity)
+	}
+	return nil
+}
+
+// bomOf returns the identity of the bom of ref, empty if it has none.
+func bomOf(ref reference) string {
+	if ref.bom == nil {
+		return ""
+	}
+	return ref.bom.Identity()
+}
+
+// parseLine splits a `blob <identity>` or `blob <identity> bom <identity>` line, or one starting with another
+// object kind, either optionally followed by annotations, see NewAnnotatedReference.
+func parseLine(line string) (reference, error) {
+	fields := strings.Split(line, " ")
+	if len(fields) < 2 {
+		return reference{}, fmt.Errorf("%w: %q", ErrMalformedReference, line)
+	}
+	kind := ObjectKind(fields[0])
+	if err := validateKind(kind); err != nil {
+		return reference{}, fmt.Errorf("%w: unsupported object type %q", ErrMalformedReference, fields[0])
+	}
+	identity, rest := fields[1], fields[2:]
+
+	var bom Identifier
+	if len(rest) > 0 && rest[0] == "bom" {
+		if len(rest) < 2 {
+			return reference{}, fmt.Errorf("%w: %q", ErrMalformedReference, line)
+		}
+		var err error
+		if bom, err = newIdentifier(rest[1]); err != nil {
+			return reference{}, err
+		}
+		rest = rest[2:]
+	}
+
+	annotations, err := parseAnnotations(rest)
+	if err != nil {
+		return reference{}, err
+	}
+	return reference{
+		kind:        kindOf(kind),
+		identity:    identity,
+		bom:         bom,
+		annotations: annotations,
+	}, nil
+}
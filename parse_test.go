@@ -0,0 +1,230 @@
+package omnibor
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSectionedString(t *testing.T) {
+	gb := NewSha1OmniBOR()
+	assert.NoError(t, gb.AddReference([]byte("hello"), nil))
+	assert.NoError(t, gb.AddReference([]byte("world"), nil))
+	assert.NoError(t, gb.AddReference([]byte("hello2"), nil))
+
+	expected := "# 0*\n" +
+		"blob 04fea06420ca60892f73becee3614f6d023a4b7f\n" +
+		"# 2*\n" +
+		"blob 23294b0610492cf55c1c4835216f20d376a287dd\n" +
+		"# b*\n" +
+		"blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\n"
+	assert.Equal(t, expected, gb.SectionedString())
+
+	parsed, err := Parse(strings.NewReader(gb.SectionedString()))
+	assert.NoError(t, err)
+	assert.Equal(t, gb.String(), parsed.String())
+	assert.Equal(t, gb.Identity(), parsed.Identity())
+}
+
+func TestParseRoundTrip(t *testing.T) {
+	doc := "blob 23294b0610492cf55c1c4835216f20d376a287dd bom dc0be356e8c2ba26e66448d97db76ad050206574\n" +
+		"blob be78cc5602c5457f144a67e574b8f98b9dc2a1a0\n"
+
+	gb, err := Parse(strings.NewReader(doc))
+	assert.NoError(t, err)
+	assert.Equal(t, doc, gb.String())
+}
+
+func TestParseSha256(t *testing.T) {
+	doc := "blob 8aec4e4876f854f688d0ebfc8f37598f38e5fd6903cccc850ca36591175aeb60\n" +
+		"blob 8df3dab4ddfa6eb2a34065cda27d95af2709d4d2658e1b5fbd145822acf42b28\n"
+
+	gb, err := Parse(strings.NewReader(doc))
+	assert.NoError(t, err)
+	assert.Equal(t, "e32e7e7761709be17ef573556a82960d489ddf0092424f7db1c91d8363dde822", gb.Identity())
+}
+
+func TestParseMalformed(t *testing.T) {
+	docs := []string{
+		"blob\n",
+		"dir 04fea06420ca60892f73becee3614f6d023a4b7f\n",
+		"blob 04fea06420ca60892f73becee3614f6d023a4b7\n",
+		"blob 04fea06420ca60892f73becee3614f6d023a4b7g\n",
+		"blob 04fea06420ca60892f73becee3614f6d023a4b7f tree 04fea06420ca60892f73becee3614f6d023a4b7f\n",
+		"blob 04fea06420ca60892f73becee3614f6d023a4b7f\n" +
+			"blob 8aec4e4876f854f688d0ebfc8f37598f38e5fd6903cccc850ca36591175aeb60\n",
+	}
+	for _, doc := range docs {
+		_, err := Parse(strings.NewReader(doc))
+		assert.Error(t, err, doc)
+	}
+}
+
+func TestParseStrict(t *testing.T) {
+	doc := "blob 04fea06420ca60892f73becee3614f6d023a4b7f\n" +
+		"blob 23294b0610492cf55c1c4835216f20d376a287dd bom dc0be356e8c2ba26e66448d97db76ad050206574\n" +
+		"blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\n"
+
+	gb, err := ParseStrict(strings.NewReader(doc))
+	assert.NoError(t, err)
+	assert.Equal(t, doc, gb.String())
+
+	sectioned, err := Parse(strings.NewReader(doc))
+	assert.NoError(t, err)
+	_, err = ParseStrict(strings.NewReader(sectioned.SectionedString()))
+	assert.NoError(t, err)
+}
+
+func TestParseStrictShuffled(t *testing.T) {
+	doc := "blob 04fea06420ca60892f73becee3614f6d023a4b7f\n" +
+		"blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\n" +
+		"blob 23294b0610492cf55c1c4835216f20d376a287dd\n"
+
+	_, err := ParseStrict(strings.NewReader(doc))
+	assert.True(t, errors.Is(err, ErrUnsorted))
+	assert.Contains(t, err.Error(), "line 3")
+
+	// the lenient parser sorts the references itself
+	gb, err := Parse(strings.NewReader(doc))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, gb.Len())
+}
+
+func TestParseDuplicateIdentity(t *testing.T) {
+	// the same object built against different boms is listed once per bom, as String writes it
+	doc := "blob 04fea06420ca60892f73becee3614f6d023a4b7f\n" +
+		"blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\n" +
+		"blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0 bom dc0be356e8c2ba26e66448d97db76ad050206574\n"
+
+	gb, err := ParseStrict(strings.NewReader(doc))
+	require.NoError(t, err)
+	assert.Equal(t, doc, gb.String())
+
+	gb, err = Parse(strings.NewReader(doc))
+	require.NoError(t, err)
+	assert.Equal(t, doc, gb.String())
+
+	// a linked reference sorts after the plain one
+	swapped := "blob 04fea06420ca60892f73becee3614f6d023a4b7f\n" +
+		"blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0 bom dc0be356e8c2ba26e66448d97db76ad050206574\n" +
+		"blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\n"
+	_, err = ParseStrict(strings.NewReader(swapped))
+	assert.True(t, errors.Is(err, ErrUnsorted))
+	assert.Contains(t, err.Error(), "line 3")
+
+	for _, repeated := range []string{
+		doc + "blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0 bom dc0be356e8c2ba26e66448d97db76ad050206574\n",
+		"blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\n" +
+			"blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0 k=v\n",
+	} {
+		_, err = ParseStrict(strings.NewReader(repeated))
+		assert.True(t, errors.Is(err, ErrDuplicateIdentity), repeated)
+		assert.False(t, errors.Is(err, ErrUnsorted))
+		assert.Contains(t, err.Error(), "b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0")
+	}
+
+	// the lenient parser drops a repeated line
+	gb, err = Parse(strings.NewReader(doc + "blob 04fea06420ca60892f73becee3614f6d023a4b7f\n"))
+	require.NoError(t, err)
+	assert.Equal(t, doc, gb.String())
+}
+
+func TestParseLineEndings(t *testing.T) {
+	expected := "blob 04fea06420ca60892f73becee3614f6d023a4b7f\n" +
+		"blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\n"
+
+	docs := []string{
+		"blob 04fea06420ca60892f73becee3614f6d023a4b7f\r\n" +
+			"blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\r\n",
+		"blob 04fea06420ca60892f73becee3614f6d023a4b7f\n" +
+			"blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0",
+		expected + "\n",
+		expected + "\r\n\r\n",
+		"# 0*\r\nblob 04fea06420ca60892f73becee3614f6d023a4b7f\r\n" +
+			"# b*\r\nblob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\r\n\r\n",
+	}
+	for _, doc := range docs {
+		gb, err := ParseStrict(strings.NewReader(doc))
+		if assert.NoError(t, err, doc) {
+			assert.Equal(t, expected, gb.String())
+			assert.Equal(t, "dc0be356e8c2ba26e66448d97db76ad050206574", gb.Identity())
+		}
+	}
+
+	gb, err := Parse(strings.NewReader("\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, gb.Len())
+}
+
+func TestParseWhitespace(t *testing.T) {
+	docs := []string{
+		"blob 04fea06420ca60892f73becee3614f6d023a4b7f\n\nblob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\n",
+		"\r\nblob 04fea06420ca60892f73becee3614f6d023a4b7f\r\n",
+		"blob 04fea06420ca60892f73becee3614f6d023a4b7f \n",
+		" blob 04fea06420ca60892f73becee3614f6d023a4b7f\n",
+		"blob  04fea06420ca60892f73becee3614f6d023a4b7f\n",
+		"blob\t04fea06420ca60892f73becee3614f6d023a4b7f\n",
+		"blob 04fea06420ca60892f73becee3614f6d023a4b7f\r\r\n",
+		"blob 04fea06420ca60892f73becee3614f6d023a4b7f\n \n",
+	}
+	for _, doc := range docs {
+		_, err := Parse(strings.NewReader(doc))
+		assert.Error(t, err, "%q", doc)
+	}
+}
+
+func TestParseMaxReferences(t *testing.T) {
+	doc := "blob 04fea06420ca60892f73becee3614f6d023a4b7f\n" +
+		"# comments do not count\n" +
+		"blob 23294b0610492cf55c1c4835216f20d376a287dd\n" +
+		"blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\n"
+
+	gb, err := ParseWithOptions(strings.NewReader(doc), ParseOptions{MaxReferences: 3})
+	require.NoError(t, err)
+	assert.Equal(t, 3, gb.Len())
+
+	gb, err = ParseWithOptions(strings.NewReader(doc), ParseOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 3, gb.Len())
+
+	_, err = ParseWithOptions(strings.NewReader(doc), ParseOptions{MaxReferences: 2})
+	assert.True(t, errors.Is(err, ErrTooManyReferences))
+	assert.Equal(t, "line 4: too many references: more than 2", err.Error())
+
+	// the limit is hit without reading the remainder of an oversized document
+	oversized := io.MultiReader(strings.NewReader(doc), iotest.ErrReader(errors.New("not reached")))
+	_, err = ParseWithOptions(oversized, ParseOptions{MaxReferences: 1})
+	assert.True(t, errors.Is(err, ErrTooManyReferences))
+
+	_, err = ParseWithOptions(strings.NewReader("blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\n"+
+		"blob 04fea06420ca60892f73becee3614f6d023a4b7f\n"), ParseOptions{Strict: true, MaxReferences: 5})
+	assert.True(t, errors.Is(err, ErrUnsorted))
+}
+
+func FuzzParse(f *testing.F) {
+	f.Add([]byte(""))
+	f.Add([]byte("blob 04fea06420ca60892f73becee3614f6d023a4b7f\n" +
+		"blob 23294b0610492cf55c1c4835216f20d376a287dd bom dc0be356e8c2ba26e66448d97db76ad050206574\n"))
+	f.Add([]byte("# 0*\r\nblob 04fea06420ca60892f73becee3614f6d023a4b7f\r\n\r\n"))
+	f.Add([]byte("tree 8aec4e4876f854f688d0ebfc8f37598f38e5fd6903cccc850ca36591175aeb60 key=value\n"))
+	f.Add([]byte("blob 04fea06420ca60892f73becee3614f6d023a4b7f bom\n"))
+
+	f.Fuzz(func(t *testing.T, doc []byte) {
+		gb, err := Parse(bytes.NewReader(doc))
+		if err != nil {
+			return
+		}
+		require.NotNil(t, gb)
+
+		// whatever parses renders a canonical document that parses back to the same tree
+		reparsed, err := ParseStrict(strings.NewReader(gb.String()))
+		require.NoError(t, err, "%q", gb.String())
+		require.Equal(t, gb.String(), reparsed.String())
+	})
+}
@@ -0,0 +1,42 @@
+package omnibor
+
+import (
+	"fmt"
+	"os"
+)
+
+// IdentifyPaths returns the identity of the tree holding a reference for every file of paths, computed with algo.
+// It is the shorthand for building a tree, adding each file with its length and taking the identity of the result.
+// Paths naming the same content add a single reference. An error is returned if algo is unknown or a path is not
+// a readable file; directories are rejected, AddTree adds the files below one.
+func IdentifyPaths(paths []string, algo HashAlgorithm) (string, error) {
+	gb, err := newTreeForAlgorithm(algo)
+	if err != nil {
+		return "", err
+	}
+	for _, path := range paths {
+		if err := gb.addPath(path); err != nil {
+			return "", err
+		}
+	}
+	return gb.Identity(), nil
+}
+
+// addPath adds a reference for the file at path, taking its length from the opened file.
+func (srv *omniBor) addPath(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s: is a directory", path)
+	}
+	_, err = srv.addGitRefID(f, nil, info.Size())
+	return withPath(err, path)
+}
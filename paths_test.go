@@ -0,0 +1,35 @@
+package omnibor
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentifyPaths(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "hello", "hello")
+	writeTestFile(t, dir, "world", "world")
+	paths := []string{filepath.Join(dir, "world"), filepath.Join(dir, "hello")}
+
+	identity, err := IdentifyPaths(paths, Sha1)
+	require.NoError(t, err)
+	assert.Equal(t, "dc0be356e8c2ba26e66448d97db76ad050206574", identity)
+
+	identity, err = IdentifyPaths(paths, Sha256)
+	require.NoError(t, err)
+	assert.Equal(t, "e32e7e7761709be17ef573556a82960d489ddf0092424f7db1c91d8363dde822", identity)
+
+	_, err = IdentifyPaths(paths, "md5")
+	assert.True(t, errors.Is(err, ErrUnknownAlgorithm))
+
+	_, err = IdentifyPaths([]string{filepath.Join(dir, "missing")}, Sha1)
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+
+	_, err = IdentifyPaths([]string{dir}, Sha1)
+	assert.Error(t, err)
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+
+	omnibor "github.com/omnibor/omnibor-go"
+)
+
+// defaultGitoidPattern matches a standalone 40 (sha1) or 64 (sha256) hex digit token.
+const defaultGitoidPattern = `\b([0-9a-f]{64}|[0-9a-f]{40})\b`
+
+// aggregateCall reads a build log line by line and adds every gitoid it finds as an existing reference.
+// The algorithm of the resulting tree is taken from the first gitoid found, later gitoids of the other algorithm
+// are logged and skipped; on EOF the tree is stored and printed.
+func aggregateCall(args ...string) error {
+	flags, opts := newFlagSet("aggregate")
+	pattern := flags.String("pattern", defaultGitoidPattern, "regular expression matching gitoids, the first capture group is used if there is one")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	args = flags.Args()
+
+	re, err := regexp.Compile(*pattern)
+	if err != nil {
+		return err
+	}
+
+	input := stdin
+	if len(args) > 0 && args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		input = f
+	}
+
+	gb, err := aggregate(input, re)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	if err := opts.addRefs(gb); err != nil {
+		log.Println(err)
+		return err
+	}
+
+	if err := writeObject(opts, gb); err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return printResult(opts, gb)
+}
+
+func aggregate(r io.Reader, re *regexp.Regexp) (omnibor.ArtifactTree, error) {
+	var gb omnibor.ArtifactTree
+
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		for _, match := range re.FindAllStringSubmatch(scanner.Text(), -1) {
+			gitoid := match[0]
+			if len(match) > 1 {
+				gitoid = match[1]
+			}
+
+			if gb == nil {
+				gb = omnibor.NewSha1OmniBOR()
+				if len(gitoid) == 64 {
+					gb = omnibor.NewSha256OmniBOR()
+				}
+			}
+			err := gb.AddExistingReference(gitoid)
+			if errors.Is(err, omnibor.ErrAlgorithmMismatch) {
+				log.Printf("line %d: skipping %s: %v", lineNo, gitoid, err)
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if gb == nil {
+		gb = omnibor.NewSha1OmniBOR()
+	}
+	return gb, nil
+}
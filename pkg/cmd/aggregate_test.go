@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	omnibor "github.com/omnibor/omnibor-go"
+	"github.com/stretchr/testify/assert"
+)
+
+const buildLog = `gcc -c hello.c -o hello.o
+omnibor: hello.o b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0
+warning: unused variable 'x' at 0xdeadbeef
+omnibor: world.o 04fea06420ca60892f73becee3614f6d023a4b7f
+linking a.out (took 1.2s) b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0
+not a gitoid: b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0ff
+`
+
+func TestAggregateCall(t *testing.T) {
+	dir := chdirTemp(t)
+	writeFile(t, filepath.Join(dir, "build.log"), buildLog)
+
+	out := captureStdout(t)
+	err := aggregateCall("build.log")
+	assert.NoError(t, err)
+	assert.Equal(t, "dc0be356e8c2ba26e66448d97db76ad050206574\n", out.String())
+	assert.Equal(t, "blob 04fea06420ca60892f73becee3614f6d023a4b7f\n"+
+		"blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\n",
+		readObject(t, ".bom", "dc0be356e8c2ba26e66448d97db76ad050206574"))
+}
+
+func TestAggregateCallStdinPattern(t *testing.T) {
+	chdirTemp(t)
+	old := stdin
+	stdin = strings.NewReader(buildLog)
+	defer func() {
+		stdin = old
+	}()
+
+	out := captureStdout(t)
+	err := aggregateCall("--print", "--pattern", `omnibor: \S+ ([0-9a-f]+)`, "-")
+	assert.NoError(t, err)
+	assert.Equal(t, "blob 04fea06420ca60892f73becee3614f6d023a4b7f\n"+
+		"blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\n", out.String())
+}
+
+func TestAggregateMixedAlgorithms(t *testing.T) {
+	chdirTemp(t)
+	old := stdin
+	stdin = strings.NewReader("04fea06420ca60892f73becee3614f6d023a4b7f\n" +
+		"8aec4e4876f854f688d0ebfc8f37598f38e5fd6903cccc850ca36591175aeb60\n")
+	defer func() {
+		stdin = old
+	}()
+
+	// the sha256 gitoid does not fit the sha1 tree started by the first one and is skipped
+	out := captureStdout(t)
+	err := aggregateCall("--print")
+	assert.NoError(t, err)
+	assert.Equal(t, "blob 04fea06420ca60892f73becee3614f6d023a4b7f\n", out.String())
+
+	// other invalid gitoids still fail the run
+	stdin = strings.NewReader("04fea06420ca60892f73becee3614f6d023a4b7f\n04fea0\n")
+	err = aggregateCall("--pattern", `[0-9a-f]+`)
+	assert.True(t, errors.Is(err, omnibor.ErrInvalidHashLength))
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	omnibor "github.com/omnibor/omnibor-go"
+)
+
+// hashCacheFile is the name of the file below the --output directory the --hash-existing cache is kept in.
+const hashCacheFile = "hash-cache.json"
+
+// hashCache is the omnibor.HashCache behind --hash-existing. It maps the absolute path of every hashed file
+// to its gitoid, trusting an entry as long as the file keeps its size and modification time.
+// Blob gitoids are not objects of the store, so the cache is the only way to know them without hashing.
+type hashCache struct {
+	lock    sync.Mutex
+	path    string
+	entries map[string]cacheEntry
+	changed bool
+}
+
+type cacheEntry struct {
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mtime"`
+	Gitoid  string `json:"gitoid"`
+}
+
+// loadHashCache reads the cache kept in path. A missing or unreadable cache yields an empty one,
+// at worst every file is hashed again.
+func loadHashCache(path string) *hashCache {
+	c := &hashCache{
+		path:    path,
+		entries: make(map[string]cacheEntry),
+	}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c
+	}
+	if err == nil {
+		err = json.Unmarshal(content, &c.entries)
+	}
+	if err != nil {
+		log.Printf("ignoring hash cache %s: %v", path, err)
+		c.entries = make(map[string]cacheEntry)
+	}
+	return c
+}
+
+func (c *hashCache) Lookup(path string, info os.FileInfo) (string, bool) {
+	key, err := filepath.Abs(path)
+	if err != nil {
+		return "", false
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || entry.Size != info.Size() || entry.ModTime != info.ModTime().UnixNano() {
+		return "", false
+	}
+	return entry.Gitoid, true
+}
+
+func (c *hashCache) Record(path string, info os.FileInfo, identity string) {
+	key, err := filepath.Abs(path)
+	if err != nil {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.entries[key] = cacheEntry{
+		Size:    info.Size(),
+		ModTime: info.ModTime().UnixNano(),
+		Gitoid:  identity,
+	}
+	c.changed = true
+}
+
+// save writes the cache back if anything was recorded.
+func (c *hashCache) save() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if !c.changed {
+		return nil
+	}
+	content, err := json.Marshal(c.entries)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(c.path, content, 0644); err != nil {
+		return err
+	}
+	c.changed = false
+	return nil
+}
+
+var _ omnibor.HashCache = (*hashCache)(nil)
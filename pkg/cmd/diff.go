@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	omnibor "github.com/omnibor/omnibor-go"
+)
+
+// diffCall prints the references of the first document missing from the second prefixed with "-",
+// then those of the second missing from the first prefixed with "+".
+func diffCall(args ...string) error {
+	if len(args) != 2 {
+		_, err := printHelp()
+		return err
+	}
+
+	a, err := parseFile(args[0])
+	if err != nil {
+		return err
+	}
+	b, err := parseFile(args[1])
+	if err != nil {
+		return err
+	}
+	return printChanges(a, b)
+}
+
+// parseFile parses the document in path, as text or in any of the --format formats, plain or gzip compressed.
+func parseFile(path string) (omnibor.ArtifactTree, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	gb, err := parseObject(content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return gb, nil
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	omnibor "github.com/omnibor/omnibor-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffCall(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a"), "blob 04fea06420ca60892f73becee3614f6d023a4b7f\n"+
+		"blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\n")
+	writeFile(t, filepath.Join(dir, "b"), "blob 23294b0610492cf55c1c4835216f20d376a287dd\n"+
+		"blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\n")
+	writeFile(t, filepath.Join(dir, "sha256"), "blob 8aec4e4876f854f688d0ebfc8f37598f38e5fd6903cccc850ca36591175aeb60\n")
+
+	out := captureStdout(t)
+	require.NoError(t, diffCall(filepath.Join(dir, "a"), filepath.Join(dir, "b")))
+	assert.Equal(t, "- blob 04fea06420ca60892f73becee3614f6d023a4b7f\n"+
+		"+ blob 23294b0610492cf55c1c4835216f20d376a287dd\n", out.String())
+
+	out.Reset()
+	require.NoError(t, diffCall(filepath.Join(dir, "a"), filepath.Join(dir, "a")))
+	assert.Equal(t, "", out.String())
+
+	err := diffCall(filepath.Join(dir, "a"), filepath.Join(dir, "sha256"))
+	assert.True(t, errors.Is(err, omnibor.ErrAlgorithmMismatch))
+
+	assert.Error(t, diffCall(filepath.Join(dir, "a"), filepath.Join(dir, "missing")))
+}
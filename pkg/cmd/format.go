@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	omnibor "github.com/omnibor/omnibor-go"
+)
+
+// The output formats selected with --format.
+const (
+	formatText  = "text"
+	formatJSON  = "json"
+	formatJSONL = "jsonl"
+)
+
+// setFormat validates the value of --format.
+func (opts *cmdOptions) setFormat(value string) error {
+	switch value {
+	case formatText, formatJSON, formatJSONL:
+		opts.format = value
+		return nil
+	default:
+		return fmt.Errorf("unknown format %q, expected text, json or jsonl", value)
+	}
+}
+
+// parseObject parses a document as text or in any of the --format formats, plain or gzip compressed,
+// so that documents printed with --format can be read back.
+func parseObject(content []byte) (omnibor.ArtifactTree, error) {
+	if bytes.HasPrefix(content, []byte{0x1f, 0x8b}) {
+		zr, err := gzip.NewReader(bytes.NewReader(content))
+		if err != nil {
+			return nil, err
+		}
+		if content, err = io.ReadAll(zr); err != nil {
+			return nil, err
+		}
+	}
+	if !bytes.HasPrefix(bytes.TrimSpace(content), []byte("{")) {
+		return omnibor.Parse(bytes.NewReader(content))
+	}
+
+	var first map[string]json.RawMessage
+	if err := json.NewDecoder(bytes.NewReader(content)).Decode(&first); err != nil {
+		return nil, err
+	}
+	if _, ok := first["references"]; ok {
+		return omnibor.ParseJSON(bytes.NewReader(content))
+	}
+	return parseJSONL(content)
+}
+
+// parseJSONL parses the references written by WriteJSONL by wrapping them into the document ParseJSON reads.
+// The hash algorithm is inferred from the length of the first reference identity.
+func parseJSONL(content []byte) (omnibor.ArtifactTree, error) {
+	var refs []json.RawMessage
+	dec := json.NewDecoder(bytes.NewReader(content))
+	for dec.More() {
+		var ref json.RawMessage
+		if err := dec.Decode(&ref); err != nil {
+			return nil, fmt.Errorf("reference %d: %w", len(refs), err)
+		}
+		refs = append(refs, ref)
+	}
+
+	var first struct {
+		Identity string `json:"identity"`
+	}
+	if err := json.Unmarshal(refs[0], &first); err != nil {
+		return nil, fmt.Errorf("reference 0: %w", err)
+	}
+	algo := omnibor.Sha1
+	if len(first.Identity) == omnibor.Sha256.HexLength() {
+		algo = omnibor.Sha256
+	}
+
+	doc, err := json.Marshal(struct {
+		Algorithm  omnibor.HashAlgorithm `json:"algorithm"`
+		References []json.RawMessage     `json:"references"`
+	}{algo, refs})
+	if err != nil {
+		return nil, err
+	}
+	return omnibor.ParseJSON(bytes.NewReader(doc))
+}
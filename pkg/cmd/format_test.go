@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	omnibor "github.com/omnibor/omnibor-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatFlag(t *testing.T) {
+	dir := chdirTemp(t)
+	writeFile(t, filepath.Join(dir, "src", "hello"), "hello")
+	writeFile(t, filepath.Join(dir, "src", "world"), "world")
+
+	previous := memoryStore
+	defer func() {
+		memoryStore = previous
+	}()
+
+	const identity = "dc0be356e8c2ba26e66448d97db76ad050206574"
+	for _, format := range []string{"text", "json", "jsonl"} {
+		for _, compress := range []bool{false, true} {
+			memoryStore = omnibor.NewMemoryStore()
+			args := []string{"--memory-store", "--print", "--format", format}
+			if compress {
+				args = append(args, "--compress")
+			}
+
+			out := captureStdout(t)
+			require.NoError(t, artifactTreeCall(append(args, "src")...), format)
+
+			switch format {
+			case "text":
+				assert.Equal(t, "blob 04fea06420ca60892f73becee3614f6d023a4b7f\n"+
+					"blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\n", out.String())
+			case "json":
+				var doc map[string]interface{}
+				require.NoError(t, json.Unmarshal(out.Bytes(), &doc))
+				assert.Equal(t, identity, doc["identity"])
+				assert.Len(t, doc["references"], 2)
+			case "jsonl":
+				lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+				require.Len(t, lines, 2)
+				for _, line := range lines {
+					var ref map[string]interface{}
+					require.NoError(t, json.Unmarshal([]byte(line), &ref), line)
+					assert.Contains(t, ref, "identity")
+				}
+			}
+
+			// the object is stored as the text document whatever the format, and what was printed reads back
+			gb, err := loadObject(memoryStore, identity)
+			require.NoError(t, err, format)
+			assert.Equal(t, identity, gb.Identity(), format)
+			printed, err := parseObject(out.Bytes())
+			require.NoError(t, err, format)
+			assert.Equal(t, identity, printed.Identity(), format)
+		}
+	}
+
+	// every object written with --format json keeps the identity its content hashes to
+	require.NoError(t, artifactTreeCall("--format", "json", "--print", "src"))
+	require.NoError(t, bomCall("--format", "json", "--compress", filepath.Join("src", "hello"), "src"))
+	corrupted, err := omnibor.VerifyStore(omnibor.NewFileObjectStore(".bom"), omnibor.Sha1)
+	require.NoError(t, err)
+	assert.Empty(t, corrupted)
+	keys, err := omnibor.NewFileObjectStore(".bom").Keys()
+	require.NoError(t, err)
+	assert.Len(t, keys, 2)
+
+	assert.Error(t, artifactTreeCall("--memory-store", "--format", "yaml", "src"))
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	omnibor "github.com/omnibor/omnibor-go"
+)
+
+// hashCall prints the gitoid of a single file, or of stdin when no file or "-" is given.
+// Nothing is stored, the gitoid is the identity a tree would record for the content.
+func hashCall(args ...string) error {
+	flags := flag.NewFlagSet("hash", flag.ContinueOnError)
+	sha256 := flags.Bool("sha256", false, "compute a sha256 gitoid instead of a sha1 one")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	args = flags.Args()
+
+	algo := omnibor.Sha1
+	if *sha256 {
+		algo = omnibor.Sha256
+	}
+
+	var gitoid string
+	var err error
+	if len(args) > 0 && args[0] != "-" {
+		gitoid, err = hashFile(args[0], algo)
+	} else {
+		gitoid, err = hashStream(stdin, algo)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(stdout, gitoid)
+	return err
+}
+
+// hashFile computes the gitoid of the file at path, streaming it as its length is known.
+func hashFile(path string, algo omnibor.HashAlgorithm) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("%s: is a directory", path)
+	}
+	return omnibor.GitOID(f, info.Size(), algo)
+}
+
+// hashStream computes the gitoid of r, which is buffered in memory as the gitoid header needs its length.
+func hashStream(r io.Reader, algo omnibor.HashAlgorithm) (string, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return omnibor.GitOID(bytes.NewReader(content), int64(len(content)), algo)
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashCall(t *testing.T) {
+	dir := chdirTemp(t)
+	writeFile(t, filepath.Join(dir, "hello"), "hello")
+
+	old := stdin
+	defer func() {
+		stdin = old
+	}()
+
+	for _, tt := range []struct {
+		args     []string
+		expected string
+	}{
+		{nil, "b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\n"},
+		{[]string{"-"}, "b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\n"},
+		{[]string{"--sha256", "-"}, "8aec4e4876f854f688d0ebfc8f37598f38e5fd6903cccc850ca36591175aeb60\n"},
+		{[]string{"hello"}, "b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\n"},
+		{[]string{"--sha256", "hello"}, "8aec4e4876f854f688d0ebfc8f37598f38e5fd6903cccc850ca36591175aeb60\n"},
+	} {
+		stdin = strings.NewReader("hello")
+		out := captureStdout(t)
+		assert.NoError(t, hashCall(tt.args...), tt.args)
+		assert.Equal(t, tt.expected, out.String(), tt.args)
+	}
+
+	assert.Error(t, hashCall("missing"))
+	assert.Error(t, hashCall(dir))
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	omnibor "github.com/omnibor/omnibor-go"
+)
+
+// pathManifest collects the relative/path <gitoid> lines of the --manifest sidecar. The document records
+// no paths, so the sidecar is the only way to tell which file a gitoid was computed from.
+type pathManifest struct {
+	lines []string
+}
+
+// option returns the option recording the files AddTree adds below root, with paths relative to root.
+// A root that is a file itself is recorded by its base name.
+func (m *pathManifest) option(root string) omnibor.Option {
+	return omnibor.WithFileIdentities(func(path, identity string) {
+		rel, err := filepath.Rel(root, path)
+		if err != nil || rel == "." {
+			rel = filepath.Base(path)
+		}
+		m.lines = append(m.lines, fmt.Sprintf("%s %s\n", filepath.ToSlash(rel), identity))
+	})
+}
+
+// save writes the recorded lines to path, sorted.
+func (m *pathManifest) save(path string) error {
+	sort.Strings(m.lines)
+	return os.WriteFile(path, []byte(strings.Join(m.lines, "")), 0644)
+}
@@ -1,18 +1,30 @@
 package cmd
 
 import (
+	"bytes"
+	"errors"
+	"flag"
 	"fmt"
-	"github.com/facebookgo/symwalk"
 	omnibor "github.com/omnibor/omnibor-go"
-	"io/ioutil"
+	"github.com/omnibor/omnibor-go/embed"
+	"io"
 	"log"
 	"os"
-	"path"
 	"path/filepath"
-	"runtime"
-	"sync"
+	"strconv"
+	"strings"
 )
 
+// stdout receives everything the CLI prints, tests swap it out to capture the output.
+var stdout io.Writer = os.Stdout
+
+// memoryStore receives the generated objects instead of the --output directory when the hidden --memory-store flag is set,
+// so tests can run the CLI without touching the filesystem.
+var memoryStore = omnibor.NewMemoryStore()
+
+// stdin is read by subcommands consuming a stream, tests swap it out to feed input.
+var stdin io.Reader = os.Stdin
+
 func Run() error {
 	if len(os.Args) < 2 {
 		return helpCall()
@@ -21,7 +33,22 @@ func Run() error {
 		return artifactTreeCall(os.Args[2:]...)
 	}
 	if os.Args[1] == "bom" {
-		return artifactTreeCall(os.Args[2:]...)
+		return bomCall(os.Args[2:]...)
+	}
+	if os.Args[1] == "verify-binary" {
+		return verifyBinaryCall(os.Args[2:]...)
+	}
+	if os.Args[1] == "aggregate" {
+		return aggregateCall(os.Args[2:]...)
+	}
+	if os.Args[1] == "verify" {
+		return verifyCall(os.Args[2:]...)
+	}
+	if os.Args[1] == "diff" {
+		return diffCall(os.Args[2:]...)
+	}
+	if os.Args[1] == "hash" {
+		return hashCall(os.Args[2:]...)
 	}
 	return helpCall()
 }
@@ -31,112 +58,301 @@ func helpCall() error {
 	return err
 }
 
+// cmdOptions holds the flags shared by the tree generating subcommands.
+type cmdOptions struct {
+	output          string
+	sectioned       bool
+	print           bool
+	refsFrom        string
+	memory          bool
+	excludes        stringList
+	follow          bool
+	compress        bool
+	workers         int
+	dryRun          bool
+	cached          bool
+	cache           *hashCache
+	manifest        string
+	paths           pathManifest
+	continueOnError bool
+	skipped         omnibor.FileErrors
+	format          string
+}
+
+// stringList collects the values of a repeatable flag.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// treeOptions returns the options AddTree is called with to add root.
+func (opts *cmdOptions) treeOptions(root string) []omnibor.Option {
+	treeOpts := []omnibor.Option{
+		omnibor.WithExclude(opts.excludes...),
+		omnibor.WithFollowSymlinks(opts.follow),
+		omnibor.WithWorkers(opts.workers),
+		omnibor.WithContinueOnError(opts.continueOnError),
+	}
+	if opts.cached {
+		if opts.cache == nil {
+			opts.cache = loadHashCache(filepath.Join(opts.output, hashCacheFile))
+		}
+		treeOpts = append(treeOpts, omnibor.WithHashCache(opts.cache))
+	}
+	if opts.manifest != "" {
+		treeOpts = append(treeOpts, opts.paths.option(root))
+	}
+	return treeOpts
+}
+
+// addTree adds the files below root to gb. With --continue-on-error files that cannot be read are logged
+// and collected in opts.skipped instead of failing the call.
+func (opts *cmdOptions) addTree(gb omnibor.ArtifactTree, root string) error {
+	err := gb.AddTree(root, opts.treeOptions(root)...)
+	var skipped omnibor.FileErrors
+	if !errors.As(err, &skipped) {
+		return err
+	}
+	for _, err := range skipped {
+		log.Println("skipped:", err)
+	}
+	opts.skipped = append(opts.skipped, skipped...)
+	return nil
+}
+
+// skippedError returns the errors of the files skipped with --continue-on-error, nil if there are none.
+func (opts *cmdOptions) skippedError() error {
+	if len(opts.skipped) == 0 {
+		return nil
+	}
+	return opts.skipped
+}
+
+// saveCache persists the --hash-existing cache, unless this is a dry run.
+func (opts *cmdOptions) saveCache() error {
+	if opts.cache == nil || opts.dryRun {
+		return nil
+	}
+	return opts.cache.save()
+}
+
+// saveManifest writes the --manifest sidecar, unless this is a dry run.
+func (opts *cmdOptions) saveManifest() error {
+	if opts.manifest == "" || opts.dryRun {
+		return nil
+	}
+	return opts.paths.save(opts.manifest)
+}
+
+// newFlagSet creates the flag set of a tree generating subcommand with the shared flags registered.
+// Subcommands may register additional flags before parsing.
+func newFlagSet(name string) (*flag.FlagSet, *cmdOptions) {
+	opts := &cmdOptions{format: formatText}
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.StringVar(&opts.output, "output", ".bom", "directory the generated objects are stored in")
+	flags.BoolVar(&opts.sectioned, "sectioned", false, "print the document grouped into sections by leading hash digit")
+	flags.BoolVar(&opts.print, "print", false, "print the generated document instead of its identity")
+	flags.StringVar(&opts.refsFrom, "refs-from", "", "file listing already computed gitoids to add, one per line")
+	flags.Var(&opts.excludes, "exclude", "skip files and directories matching the glob, may be repeated")
+	flags.BoolVar(&opts.follow, "follow-symlinks", true, "reference the targets of symbolic links rather than the links themselves")
+	flags.BoolVar(&opts.compress, "compress", false, "store the generated objects gzip compressed")
+	flags.BoolVar(&opts.cached, "hash-existing", false, "reuse the gitoids of files unchanged since they were last hashed")
+	flags.StringVar(&opts.manifest, "manifest", "", "file the relative path and gitoid of every hashed file are written to")
+	flags.BoolVar(&opts.continueOnError, "continue-on-error", false, "skip files that cannot be read and report them once the tree is built")
+	flags.Func("format", "format of the documents printed with --print: text, json or jsonl", opts.setFormat)
+	flags.BoolVar(&opts.dryRun, "dry-run", false, "log where the generated objects would be stored without storing them")
+	flags.Func("workers", "number of files hashed concurrently, at least 1", func(value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		if n < 1 {
+			return fmt.Errorf("%d workers, at least 1 is required", n)
+		}
+		opts.workers = n
+		return nil
+	})
+	// not documented in printHelp, this is for testing only
+	flags.BoolVar(&opts.memory, "memory-store", false, "keep the generated objects in memory instead of the output directory")
+	return flags, opts
+}
+
+// store returns where the generated objects are written to.
+func (opts *cmdOptions) store() omnibor.ObjectStore {
+	if opts.memory {
+		return memoryStore
+	}
+	return omnibor.NewFileObjectStore(opts.output)
+}
+
+// addRefs adds the gitoids listed in the --refs-from file, if any, to gb.
+func (opts *cmdOptions) addRefs(gb omnibor.ArtifactTree) error {
+	if opts.refsFrom == "" {
+		return nil
+	}
+	return addRefsFrom(opts.refsFrom, gb)
+}
+
 func artifactTreeCall(args ...string) error {
-	wg := startAgents()
-	if len(args) == 0 {
+	flags, opts := newFlagSet("artifact-tree")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	args = flags.Args()
+
+	if len(args) == 0 && opts.refsFrom == "" {
 		_, err := printHelp()
 		return err
 	}
 
 	gb := omnibor.NewSha1OmniBOR()
 	for i := 0; i < len(args); i++ {
-		if err := addPathToOmniBOR(gb, args[i], agentChan); err != nil {
+		if err := opts.addTree(gb, args[i]); err != nil {
 			log.Println(args[i], err)
 			return err
 		}
 	}
-
-	close(agentChan)
-	wg.Wait()
+	if err := opts.addRefs(gb); err != nil {
+		log.Println(err)
+		return err
+	}
 
 	// generate target omnibor with artifact tree
-	if err := writeObject(".bom", gb); err != nil {
+	if err := writeObject(opts, gb); err != nil {
+		log.Println(err)
+		return err
+	}
+	if err := opts.saveCache(); err != nil {
+		log.Println(err)
+		return err
+	}
+	if err := opts.saveManifest(); err != nil {
 		log.Println(err)
 		return err
 	}
 
-	fmt.Println(gb.Identity())
-
-	return nil
+	if err := printResult(opts, gb); err != nil {
+		return err
+	}
+	return opts.skippedError()
 }
 
-var agentChan = make(chan fileEvent)
+// bomCall builds the artifact tree of the input files, then links the artifact to it
+// by creating a second tree whose only reference carries the input tree's identity as its bom.
+func bomCall(args ...string) error {
+	flags, opts := newFlagSet("bom")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	args = flags.Args()
 
-func startAgents() *sync.WaitGroup {
-	agentCount := 0
-	wg := &sync.WaitGroup{}
-	if runtime.GOMAXPROCS(0) < runtime.NumCPU() {
-		agentCount = runtime.GOMAXPROCS(0)
-	} else {
-		agentCount = runtime.NumCPU()
+	if len(args) < 2 {
+		_, err := printHelp()
+		return err
+	}
+	artifact, inputs := args[0], args[1:]
+
+	inputTree := omnibor.NewSha1OmniBOR()
+	for i := 0; i < len(inputs); i++ {
+		if err := opts.addTree(inputTree, inputs[i]); err != nil {
+			log.Println(inputs[i], err)
+			return err
+		}
 	}
-	for i := 0; i < agentCount; i++ {
-		wg.Add(1)
-		go agent(agentChan, wg)
+	if err := opts.addRefs(inputTree); err != nil {
+		log.Println(err)
+		return err
 	}
-	return wg
-}
 
-func writeObject(prefix string, gb omnibor.ArtifactTree) error {
-	objs := gb.Identity()
-	objectDir := path.Join(prefix, "object", objs[0:2])
-	objectPath := path.Join(objectDir, objs[2:])
-	if err := os.MkdirAll(objectDir, 0755); err != nil {
+	gb := omnibor.NewSha1OmniBOR()
+	if err := addFileToOmniBOR(artifact, gb, inputTree); err != nil {
+		log.Println(artifact, err)
+		return err
+	}
+
+	// the input tree is staged first so the link from gb never dangles
+	if err := writeObjects(opts, inputTree, gb); err != nil {
 		log.Println(err)
 		return err
 	}
-	if err := ioutil.WriteFile(objectPath, []byte(gb.String()), 0644); err != nil {
+	if err := opts.saveCache(); err != nil {
+		log.Println(err)
+		return err
+	}
+	if err := opts.saveManifest(); err != nil {
+		log.Println(err)
 		return err
 	}
-	return nil
-}
 
-func addPathToOmniBOR(gb omnibor.ArtifactTree, fileName string, agentChan chan<- fileEvent) error {
-	err := symwalk.Walk(fileName, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		path, err = filepath.EvalSymlinks(path)
-		if err != nil {
-			log.Println("ERROR", err)
-			return err
-		}
-		info, err = os.Stat(path)
-		if err != nil {
-			log.Println("ERROR", err)
-			return err
-		}
-		if !info.IsDir() {
-			e := fileEvent{
-				path: path,
-				info: info,
-				gb:   gb,
-			}
-			agentChan <- e
-			return nil
-		}
-		return nil
-	})
-	return err
+	if err := printResult(opts, gb); err != nil {
+		return err
+	}
+	return opts.skippedError()
 }
 
-type fileEvent struct {
-	path string
-	info os.FileInfo
-	gb   omnibor.ArtifactTree
+// verifyBinaryCall rebuilds the artifact tree of dir and checks it against the identity embedded in binary.
+func verifyBinaryCall(args ...string) error {
+	if len(args) != 2 {
+		_, err := printHelp()
+		return err
+	}
+	binary, dir := args[0], args[1]
+
+	embedded, err := embed.ExtractELF(binary)
+	if err != nil {
+		return err
+	}
+
+	var gb omnibor.ArtifactTree
+	switch len(embedded.Identity()) {
+	case 64:
+		gb = omnibor.NewSha256OmniBOR()
+	default:
+		gb = omnibor.NewSha1OmniBOR()
+	}
+	if err := gb.AddTree(dir); err != nil {
+		log.Println(dir, err)
+		return err
+	}
+
+	if gb.Identity() != embedded.Identity() {
+		return fmt.Errorf("%s embeds %s but %s produces %s: %w",
+			binary, embedded.Identity(), dir, gb.Identity(), omnibor.ErrIdentityMismatch)
+	}
+
+	_, err = fmt.Fprintln(stdout, gb.Identity())
+	return err
 }
 
-func agent(e <-chan fileEvent, wg *sync.WaitGroup) {
-	defer wg.Done()
-	for ev := range e {
-		err2 := addFileToOmniBOR(ev.path, ev.info, ev.gb, nil)
-		if err2 != nil {
-			log.Println("ERROR", ev.path)
+// printResult prints the identity of gb, or with --print the document itself so it can be piped into other tools.
+func printResult(opts *cmdOptions, gb omnibor.ArtifactTree) error {
+	var err error
+	switch {
+	case opts.print && opts.format == formatJSON:
+		_, err = fmt.Fprintln(stdout, string(gb.CanonicalJSON()))
+	case opts.print && opts.format == formatJSONL:
+		err = gb.WriteJSONL(stdout)
+	case opts.print && opts.sectioned:
+		_, err = fmt.Fprint(stdout, gb.SectionedString())
+	case opts.print:
+		_, err = fmt.Fprint(stdout, gb.String())
+	case opts.sectioned:
+		if _, err = fmt.Fprint(stdout, gb.SectionedString()); err == nil {
+			_, err = fmt.Fprintln(stdout, gb.Identity())
 		}
+	default:
+		_, err = fmt.Fprintln(stdout, gb.Identity())
 	}
+	return err
 }
 
-func addFileToOmniBOR(path string, info os.FileInfo, gb omnibor.ArtifactTree, identifier omnibor.Identifier) error {
+func addFileToOmniBOR(path string, gb omnibor.ArtifactTree, identifier omnibor.Identifier) error {
 	f, err := os.Open(path)
 	if err != nil {
 		return err
@@ -148,23 +364,162 @@ func addFileToOmniBOR(path string, info os.FileInfo, gb omnibor.ArtifactTree, id
 		}
 	}(f)
 
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
 	if err := gb.AddReferenceFromReader(f, identifier, info.Size()); err != nil {
+		// the file changed size since it was stat'ed, name it so the message is actionable
+		var lengthErr *omnibor.ContentLengthError
+		if errors.As(err, &lengthErr) {
+			lengthErr.Path = path
+		}
+		return err
+	}
+	return nil
+}
+
+func writeObject(opts *cmdOptions, gb omnibor.ArtifactTree) error {
+	if opts.dryRun {
+		return logObjects(opts, gb)
+	}
+	content, err := opts.object(gb)
+	if err != nil {
 		return err
 	}
+	return opts.store().Put(gb.Identity(), content)
+}
+
+// writeObjects stores every tree, making them visible in the order given.
+// File stores write them in a single batch.
+func writeObjects(opts *cmdOptions, trees ...omnibor.ArtifactTree) error {
+	if opts.dryRun {
+		return logObjects(opts, trees...)
+	}
+	fileStore, ok := opts.store().(*omnibor.FileObjectStore)
+	if !ok {
+		for _, gb := range trees {
+			if err := writeObject(opts, gb); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	batch := fileStore.Batch()
+	for _, gb := range trees {
+		content, err := opts.object(gb)
+		if err != nil {
+			return err
+		}
+		if err := batch.Put(gb.Identity(), content); err != nil {
+			return err
+		}
+	}
+	return batch.Commit()
+}
+
+// logObjects logs the identity of every tree and the file it would be stored in, for --dry-run.
+func logObjects(opts *cmdOptions, trees ...omnibor.ArtifactTree) error {
+	fileStore, ok := opts.store().(*omnibor.FileObjectStore)
+	for _, gb := range trees {
+		if !ok {
+			log.Printf("dry run: would store %s in memory", gb.Identity())
+			continue
+		}
+		path, err := fileStore.ObjectPath(gb.Identity())
+		if err != nil {
+			return err
+		}
+		log.Printf("dry run: would store %s at %s", gb.Identity(), path)
+	}
 	return nil
 }
 
+// object returns the content gb is stored as, its document or with --compress the gzip compressed document.
+// Objects are always stored as the canonical text document, whose gitoid is their identity; --format only
+// changes what is printed.
+func (opts *cmdOptions) object(gb omnibor.ArtifactTree) ([]byte, error) {
+	if !opts.compress {
+		return []byte(gb.String()), nil
+	}
+	var buf bytes.Buffer
+	if err := gb.WriteCompressed(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
 func printHelp() (int, error) {
-	return fmt.Println(`
+	return fmt.Fprintln(stdout, `
        omnibor (v0.0.1) - Generate OmniBOR ADG from files
 
        **USAGE**
-       omnibor artifact-tree [files]
-       omnibor bom [artifact-file] [artifact-tree-files [artifact-tree files...]]
+       omnibor artifact-tree [options] [files]
+       omnibor bom [options] [artifact-file] [artifact-tree-files [artifact-tree files...]]
+       omnibor verify-binary [binary] [dir]
+       omnibor aggregate [options] [--pattern regex] [log-file]
+       omnibor verify [options] [bom-identity] [files...]
+       omnibor diff [bom-file-a] [bom-file-b]
+       omnibor hash [--sha256] [file]
 
        omnibor will create a .bom/ directory in the current working
        directory and store generated OmniBOR ADGs in .bom/
 
+       aggregate collects the gitoids printed in a build log (stdin when no
+       file is given) into a single artifact tree. The tree uses the hash
+       algorithm of the first gitoid found, gitoids of the other algorithm
+       are logged and skipped.
+
+       verify rebuilds the artifact tree of the files and checks that it
+       still has the recorded identity. On a mismatch the references added
+       (+) and removed (-) since are listed if the recorded document is in
+       the --output store, and omnibor exits non-zero.
+
+       diff lists the references removed (-) from and added (+) to the
+       first document in the second one. Both must use the same hash
+       algorithm.
+
+       hash prints the gitoid of a single file, or of stdin when no file or
+       - is given, without storing anything.
+
+       verify-binary checks that the OmniBOR identity embedded in an ELF
+       binary matches the artifact tree built from dir.
+
+       **OPTIONS**
+       --compress     store generated OmniBOR ADGs gzip compressed
+       --continue-on-error
+                      skip files that cannot be read instead of stopping,
+                      log them and exit non-zero once the ADGs are stored
+       --dry-run      log where the generated OmniBOR ADGs would be stored
+                      instead of storing them
+       --exclude glob skip files and directories matching glob, either by
+                      their path below the walked directory or by name;
+                      may be repeated
+       --format f     print documents with --print as f: text (the
+                      default), json or jsonl, one JSON object per
+                      reference; stored ADGs are always text
+       --follow-symlinks=false
+                      reference symbolic links themselves, as git does,
+                      instead of the files and directories they point to
+       --hash-existing
+                      reuse the gitoids of files whose size and modification
+                      time did not change since they were hashed, kept in
+                      hash-cache.json below the --output directory
+       --manifest f   write a "relative/path gitoid" line for every hashed
+                      file to f, the paths relative to the directory given;
+                      the generated OmniBOR ADGs are unchanged
+       --output dir   store generated OmniBOR ADGs in dir instead of .bom/
+       --pattern re   aggregate: regular expression matching gitoids, the
+                      first capture group is used if there is one
+       --print        print the generated document instead of its identity
+       --refs-from f  add the already computed gitoids listed in f, one per
+                      line, without hashing the artifacts again
+       --sectioned    print the document grouped by leading hash digit
+       --sha256       hash: compute a sha256 gitoid instead of a sha1 one
+       --workers n    hash n files concurrently instead of one per CPU
+
        **LEGAL**
        omnibor (v0.0.2) Copyright 2023 omnibor-go contributors
        SPDX-License-Identifier: Apache-2.0`)
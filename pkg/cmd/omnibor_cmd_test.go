@@ -0,0 +1,331 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	omnibor "github.com/omnibor/omnibor-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// chdirTemp moves the test into a fresh temporary directory so generated .bom stores don't leak into the source tree.
+func chdirTemp(t *testing.T) string {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() {
+		_ = os.Chdir(wd)
+	})
+	return dir
+}
+
+// captureStdout redirects the CLI output into a buffer for the duration of the test.
+func captureStdout(t *testing.T) *bytes.Buffer {
+	buf := &bytes.Buffer{}
+	previous := stdout
+	stdout = buf
+	t.Cleanup(func() {
+		stdout = previous
+	})
+	return buf
+}
+
+func writeFile(t *testing.T, path, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func readObject(t *testing.T, prefix, identity string) string {
+	content, err := os.ReadFile(filepath.Join(prefix, "object", identity[:2], identity[2:]))
+	require.NoError(t, err)
+	return string(content)
+}
+
+func TestBomCall(t *testing.T) {
+	dir := chdirTemp(t)
+	writeFile(t, filepath.Join(dir, "src", "hello"), "hello")
+	writeFile(t, filepath.Join(dir, "src", "world"), "world")
+	writeFile(t, filepath.Join(dir, "artifact"), "hello2")
+
+	err := bomCall("artifact", "src")
+	assert.NoError(t, err)
+
+	inputTree := readObject(t, ".bom", "dc0be356e8c2ba26e66448d97db76ad050206574")
+	assert.Equal(t, "blob 04fea06420ca60892f73becee3614f6d023a4b7f\n"+
+		"blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\n", inputTree)
+
+	inputID, err := omnibor.NewIdentifier("dc0be356e8c2ba26e66448d97db76ad050206574")
+	require.NoError(t, err)
+	expected := omnibor.NewSha1OmniBOR()
+	require.NoError(t, expected.AddReference([]byte("hello2"), inputID))
+
+	artifactTree := readObject(t, ".bom", expected.Identity())
+	assert.Equal(t, "blob 23294b0610492cf55c1c4835216f20d376a287dd bom dc0be356e8c2ba26e66448d97db76ad050206574\n", artifactTree)
+}
+
+func TestBomCallMissingArtifact(t *testing.T) {
+	dir := chdirTemp(t)
+	writeFile(t, filepath.Join(dir, "src", "hello"), "hello")
+
+	err := bomCall("artifact", "src")
+	assert.Error(t, err)
+}
+
+func TestArtifactTreeCallOutput(t *testing.T) {
+	dir := chdirTemp(t)
+	writeFile(t, filepath.Join(dir, "src", "hello"), "hello")
+	writeFile(t, filepath.Join(dir, "src", "world"), "world")
+
+	absolute := filepath.Join(t.TempDir(), "store")
+	err := artifactTreeCall("--output", absolute, "src")
+	assert.NoError(t, err)
+	assert.Equal(t, "blob 04fea06420ca60892f73becee3614f6d023a4b7f\n"+
+		"blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\n",
+		readObject(t, absolute, "dc0be356e8c2ba26e66448d97db76ad050206574"))
+
+	err = artifactTreeCall("--output", filepath.Join("nested", "store"), "src")
+	assert.NoError(t, err)
+	readObject(t, filepath.Join(dir, "nested", "store"), "dc0be356e8c2ba26e66448d97db76ad050206574")
+
+	_, err = os.Stat(filepath.Join(dir, ".bom"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestArtifactTreeCallExclude(t *testing.T) {
+	dir := chdirTemp(t)
+	writeFile(t, filepath.Join(dir, "src", "hello"), "hello")
+	writeFile(t, filepath.Join(dir, "src", "world"), "world")
+	writeFile(t, filepath.Join(dir, "src", ".git", "HEAD"), "hello2")
+	writeFile(t, filepath.Join(dir, "src", "build", "out", "independent"), "independent")
+	writeFile(t, filepath.Join(dir, "src", "build", "opaque"), "opaque")
+
+	out := captureStdout(t)
+	err := artifactTreeCall("--print", "--exclude", ".git", "--exclude", "build", "src")
+	assert.NoError(t, err)
+	assert.Equal(t, "blob 04fea06420ca60892f73becee3614f6d023a4b7f\n"+
+		"blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\n", out.String())
+}
+
+func TestArtifactTreeCallFollowSymlinks(t *testing.T) {
+	dir := chdirTemp(t)
+	writeFile(t, filepath.Join(dir, "src", "hello"), "hello")
+	writeFile(t, filepath.Join(dir, "world"), "world")
+	require.NoError(t, os.Symlink(filepath.Join("..", "world"), filepath.Join(dir, "src", "world")))
+	require.NoError(t, os.Symlink(".", filepath.Join(dir, "src", "loop")))
+
+	out := captureStdout(t)
+	err := artifactTreeCall("src")
+	assert.NoError(t, err)
+	assert.Equal(t, "dc0be356e8c2ba26e66448d97db76ad050206574\n", out.String())
+
+	out.Reset()
+	err = artifactTreeCall("--print", "--follow-symlinks=false", "src")
+	assert.NoError(t, err)
+	expected := omnibor.NewSha1OmniBOR()
+	require.NoError(t, expected.AddReference([]byte("hello"), nil))
+	require.NoError(t, expected.AddReference([]byte(filepath.Join("..", "world")), nil))
+	require.NoError(t, expected.AddReference([]byte("."), nil))
+	assert.Equal(t, expected.String(), out.String())
+}
+
+func TestArtifactTreeCallPrint(t *testing.T) {
+	dir := chdirTemp(t)
+	writeFile(t, filepath.Join(dir, "src", "hello"), "hello")
+	writeFile(t, filepath.Join(dir, "src", "world"), "world")
+
+	out := captureStdout(t)
+	err := artifactTreeCall("src")
+	assert.NoError(t, err)
+	assert.Equal(t, "dc0be356e8c2ba26e66448d97db76ad050206574\n", out.String())
+
+	out.Reset()
+	err = artifactTreeCall("--print", "src")
+	assert.NoError(t, err)
+	assert.Equal(t, "blob 04fea06420ca60892f73becee3614f6d023a4b7f\n"+
+		"blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\n", out.String())
+	assert.Equal(t, out.String(), readObject(t, ".bom", "dc0be356e8c2ba26e66448d97db76ad050206574"))
+}
+
+func TestVerifyBinaryCall(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "hello"), "hello")
+	writeFile(t, filepath.Join(dir, "world"), "world")
+
+	out := captureStdout(t)
+	err := verifyBinaryCall(filepath.Join("testdata", "match.elf"), dir)
+	assert.NoError(t, err)
+	assert.Equal(t, "dc0be356e8c2ba26e66448d97db76ad050206574\n", out.String())
+
+	err = verifyBinaryCall(filepath.Join("testdata", "mismatch.elf"), dir)
+	assert.True(t, errors.Is(err, omnibor.ErrIdentityMismatch))
+}
+
+func TestMemoryStoreFlag(t *testing.T) {
+	dir := chdirTemp(t)
+	writeFile(t, filepath.Join(dir, "src", "hello"), "hello")
+	writeFile(t, filepath.Join(dir, "src", "world"), "world")
+	writeFile(t, filepath.Join(dir, "artifact"), "hello2")
+
+	previous := memoryStore
+	memoryStore = omnibor.NewMemoryStore()
+	defer func() {
+		memoryStore = previous
+	}()
+
+	out := captureStdout(t)
+	err := bomCall("--memory-store", "artifact", "src")
+	assert.NoError(t, err)
+	assert.NoDirExists(t, filepath.Join(dir, ".bom"))
+
+	identity := strings.TrimSpace(out.String())
+	keys, err := memoryStore.Keys()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dc0be356e8c2ba26e66448d97db76ad050206574", identity}, keys)
+
+	content, err := memoryStore.Get(identity)
+	require.NoError(t, err)
+	assert.Equal(t, "blob 23294b0610492cf55c1c4835216f20d376a287dd bom dc0be356e8c2ba26e66448d97db76ad050206574\n", string(content))
+}
+
+func TestCompressFlag(t *testing.T) {
+	dir := chdirTemp(t)
+	writeFile(t, filepath.Join(dir, "src", "hello"), "hello")
+	writeFile(t, filepath.Join(dir, "src", "world"), "world")
+
+	previous := memoryStore
+	memoryStore = omnibor.NewMemoryStore()
+	defer func() {
+		memoryStore = previous
+	}()
+
+	out := captureStdout(t)
+	assert.NoError(t, artifactTreeCall("--memory-store", "--compress", "src"))
+	assert.Equal(t, "dc0be356e8c2ba26e66448d97db76ad050206574\n", out.String())
+
+	content, err := memoryStore.Get("dc0be356e8c2ba26e66448d97db76ad050206574")
+	require.NoError(t, err)
+	gb, err := omnibor.ParseCompressed(bytes.NewReader(content))
+	require.NoError(t, err)
+	assert.Equal(t, "dc0be356e8c2ba26e66448d97db76ad050206574", gb.Identity())
+}
+
+func TestArtifactTreeCallWorkers(t *testing.T) {
+	dir := chdirTemp(t)
+	for i := 0; i < 20; i++ {
+		writeFile(t, filepath.Join(dir, "src", fmt.Sprintf("dir%d", i%3), fmt.Sprintf("file%d", i)), fmt.Sprintf("content %d", i))
+	}
+
+	out := captureStdout(t)
+	require.NoError(t, artifactTreeCall("--print", "--workers", "1", "src"))
+	sequential := out.String()
+
+	out.Reset()
+	require.NoError(t, artifactTreeCall("--print", "--workers", "8", "src"))
+	assert.Equal(t, sequential, out.String())
+	assert.Equal(t, 20, strings.Count(sequential, "\n"))
+
+	assert.Error(t, artifactTreeCall("--workers", "0", "src"))
+	assert.Error(t, artifactTreeCall("--workers", "many", "src"))
+}
+
+func TestDryRunFlag(t *testing.T) {
+	dir := chdirTemp(t)
+	writeFile(t, filepath.Join(dir, "src", "hello"), "hello")
+	writeFile(t, filepath.Join(dir, "src", "world"), "world")
+	writeFile(t, filepath.Join(dir, "artifact"), "hello2")
+
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	out := captureStdout(t)
+	require.NoError(t, artifactTreeCall("--dry-run", "--print", "src"))
+	assert.Equal(t, "blob 04fea06420ca60892f73becee3614f6d023a4b7f\n"+
+		"blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\n", out.String())
+	assert.Contains(t, logged.String(), "would store dc0be356e8c2ba26e66448d97db76ad050206574 at "+
+		filepath.Join(".bom", "object", "dc", "0be356e8c2ba26e66448d97db76ad050206574"))
+
+	require.NoError(t, bomCall("--dry-run", "artifact", "src"))
+	assert.Equal(t, 3, strings.Count(logged.String(), "would store"))
+	assert.NoDirExists(t, filepath.Join(dir, ".bom"))
+}
+
+func TestHashExistingFlag(t *testing.T) {
+	dir := chdirTemp(t)
+	writeFile(t, filepath.Join(dir, "src", "hello"), "hello")
+	writeFile(t, filepath.Join(dir, "src", "world"), "world")
+
+	out := captureStdout(t)
+	require.NoError(t, artifactTreeCall("--hash-existing", "src"))
+	assert.Equal(t, "dc0be356e8c2ba26e66448d97db76ad050206574\n", out.String())
+	assert.FileExists(t, filepath.Join(dir, ".bom", hashCacheFile))
+
+	// same size and modification time, the cached gitoid is used without reading the file
+	world := filepath.Join(dir, "src", "world")
+	info, err := os.Stat(world)
+	require.NoError(t, err)
+	writeFile(t, world, "WORLD")
+	require.NoError(t, os.Chtimes(world, info.ModTime(), info.ModTime()))
+
+	out.Reset()
+	require.NoError(t, artifactTreeCall("--hash-existing", "src"))
+	assert.Equal(t, "dc0be356e8c2ba26e66448d97db76ad050206574\n", out.String())
+
+	// without the flag, or once the modification time changes, the file is hashed again
+	out.Reset()
+	require.NoError(t, artifactTreeCall("src"))
+	assert.NotEqual(t, "dc0be356e8c2ba26e66448d97db76ad050206574\n", out.String())
+	changed := out.String()
+
+	require.NoError(t, os.Chtimes(world, info.ModTime(), info.ModTime().Add(time.Second)))
+	out.Reset()
+	require.NoError(t, artifactTreeCall("--hash-existing", "src"))
+	assert.Equal(t, changed, out.String())
+}
+
+func TestManifestFlag(t *testing.T) {
+	dir := chdirTemp(t)
+	writeFile(t, filepath.Join(dir, "src", "hello"), "hello")
+	writeFile(t, filepath.Join(dir, "src", "sub", "world"), "world")
+
+	out := captureStdout(t)
+	manifest := filepath.Join(dir, "paths.txt")
+	require.NoError(t, artifactTreeCall("--manifest", manifest, filepath.Join(dir, "src")))
+	assert.Equal(t, "dc0be356e8c2ba26e66448d97db76ad050206574\n", out.String())
+
+	content, err := os.ReadFile(manifest)
+	require.NoError(t, err)
+	assert.Equal(t, "hello b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\n"+
+		"sub/world 04fea06420ca60892f73becee3614f6d023a4b7f\n", string(content))
+	assert.Equal(t, "blob 04fea06420ca60892f73becee3614f6d023a4b7f\nblob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\n",
+		readObject(t, ".bom", "dc0be356e8c2ba26e66448d97db76ad050206574"))
+}
+
+func TestContinueOnErrorFlag(t *testing.T) {
+	dir := chdirTemp(t)
+	writeFile(t, filepath.Join(dir, "src", "hello"), "hello")
+	writeFile(t, filepath.Join(dir, "src", "world"), "world")
+	// a link to a missing file cannot be read, whatever the privileges of the user
+	require.NoError(t, os.Symlink(filepath.Join(dir, "missing"), filepath.Join(dir, "src", "broken")))
+
+	out := captureStdout(t)
+	err := artifactTreeCall("src")
+	require.Error(t, err)
+	assert.Empty(t, out.String())
+	assert.NoDirExists(t, filepath.Join(dir, ".bom"))
+
+	err = artifactTreeCall("--continue-on-error", "src")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "missing")
+	assert.Equal(t, "dc0be356e8c2ba26e66448d97db76ad050206574\n", out.String())
+	assert.FileExists(t, filepath.Join(dir, ".bom", "object", "dc", "0be356e8c2ba26e66448d97db76ad050206574"))
+}
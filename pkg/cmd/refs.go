@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	omnibor "github.com/omnibor/omnibor-go"
+)
+
+// addRefsFrom adds every gitoid listed in path, one per line, to gb without re-hashing the artifacts.
+// Surrounding whitespace and empty lines are ignored, any other malformed line fails with its line number.
+func addRefsFrom(path string, gb omnibor.ArtifactTree) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		gitoid := strings.TrimSpace(scanner.Text())
+		if gitoid == "" {
+			continue
+		}
+		if err := gb.AddExistingReference(gitoid); err != nil {
+			return fmt.Errorf("%s:%d: %w", path, lineNo, err)
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	omnibor "github.com/omnibor/omnibor-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArtifactTreeCallRefsFrom(t *testing.T) {
+	dir := chdirTemp(t)
+	writeFile(t, filepath.Join(dir, "src", "hello"), "hello")
+	writeFile(t, filepath.Join(dir, "refs"), "04fea06420ca60892f73becee3614f6d023a4b7f\n"+
+		"\n"+
+		"  23294b0610492cf55c1c4835216f20d376a287dd  \n")
+
+	out := captureStdout(t)
+	err := artifactTreeCall("--print", "--refs-from", "refs", "src")
+	assert.NoError(t, err)
+	assert.Equal(t, "blob 04fea06420ca60892f73becee3614f6d023a4b7f\n"+
+		"blob 23294b0610492cf55c1c4835216f20d376a287dd\n"+
+		"blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\n", out.String())
+
+	// the artifact tree may be built from the list alone
+	out.Reset()
+	err = artifactTreeCall("--refs-from", "refs")
+	assert.NoError(t, err)
+	assert.Equal(t, "blob 04fea06420ca60892f73becee3614f6d023a4b7f\n"+
+		"blob 23294b0610492cf55c1c4835216f20d376a287dd\n", readObject(t, ".bom", out.String()[:40]))
+}
+
+func TestArtifactTreeCallRefsFromMalformed(t *testing.T) {
+	dir := chdirTemp(t)
+	writeFile(t, filepath.Join(dir, "src", "hello"), "hello")
+	writeFile(t, filepath.Join(dir, "refs"), "04fea06420ca60892f73becee3614f6d023a4b7f\n"+
+		"23294b0610492cf55c1c4835216f20d376a287dd\n"+
+		"not a gitoid\n")
+
+	err := artifactTreeCall("--refs-from", "refs", "src")
+	assert.True(t, errors.Is(err, omnibor.ErrInvalidHashLength))
+	assert.Contains(t, err.Error(), "refs:3:")
+
+	writeFile(t, filepath.Join(dir, "refs"), "04fea06420ca60892f73becee3614f6d023a4b7g\n")
+	err = bomCall("--refs-from", "refs", "src/hello", "src")
+	assert.True(t, errors.Is(err, omnibor.ErrInvalidHex))
+	assert.Contains(t, err.Error(), "refs:1:")
+}
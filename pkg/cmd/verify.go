@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	omnibor "github.com/omnibor/omnibor-go"
+)
+
+// verifyCall rebuilds the artifact tree of the given paths and checks that it still has the recorded identity.
+// On a mismatch the references that differ from the recorded document are printed, prefixed with "-" when they
+// are no longer produced and "+" when they are new, provided the recorded document is in the store.
+func verifyCall(args ...string) error {
+	flags, opts := newFlagSet("verify")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	args = flags.Args()
+
+	if len(args) < 2 {
+		_, err := printHelp()
+		return err
+	}
+	recorded, paths := args[0], args[1:]
+
+	var gb omnibor.ArtifactTree
+	switch len(recorded) {
+	case 40:
+		gb = omnibor.NewSha1OmniBOR()
+	case 64:
+		gb = omnibor.NewSha256OmniBOR()
+	default:
+		return fmt.Errorf("%s: %w", recorded, omnibor.ErrInvalidHashLength)
+	}
+	for _, path := range paths {
+		if err := opts.addTree(gb, path); err != nil {
+			log.Println(path, err)
+			return err
+		}
+	}
+	if err := opts.addRefs(gb); err != nil {
+		log.Println(err)
+		return err
+	}
+
+	if gb.Identity() == recorded {
+		if _, err := fmt.Fprintln(stdout, gb.Identity()); err != nil {
+			return err
+		}
+		return opts.skippedError()
+	}
+
+	mismatch := fmt.Errorf("recorded %s but the paths produce %s: %w", recorded, gb.Identity(), omnibor.ErrIdentityMismatch)
+	previous, err := loadObject(opts.store(), recorded)
+	if errors.Is(err, omnibor.ErrObjectNotFound) {
+		log.Printf("%s is not in the store, differing references cannot be listed", recorded)
+		return mismatch
+	}
+	if err != nil {
+		return err
+	}
+	if err := printChanges(previous, gb); err != nil {
+		return err
+	}
+	return mismatch
+}
+
+// loadObject parses the document stored under identity, which may have been stored with --compress.
+func loadObject(store omnibor.ObjectStore, identity string) (omnibor.ArtifactTree, error) {
+	content, err := store.Get(identity)
+	if err != nil {
+		return nil, err
+	}
+	return parseObject(content)
+}
+
+// printChanges prints the references of previous missing from current prefixed with "-",
+// then those of current missing from previous prefixed with "+", each in document order.
+func printChanges(previous, current omnibor.ArtifactTree) error {
+	added, removed, err := omnibor.Diff(previous, current)
+	if err != nil {
+		return err
+	}
+	for _, ref := range removed {
+		if _, err := fmt.Fprint(stdout, "- ", ref.String()); err != nil {
+			return err
+		}
+	}
+	for _, ref := range added {
+		if _, err := fmt.Fprint(stdout, "+ ", ref.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
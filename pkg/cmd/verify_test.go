@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	omnibor "github.com/omnibor/omnibor-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyCall(t *testing.T) {
+	dir := chdirTemp(t)
+	writeFile(t, filepath.Join(dir, "src", "hello"), "hello")
+	writeFile(t, filepath.Join(dir, "src", "world"), "world")
+
+	out := captureStdout(t)
+	require.NoError(t, artifactTreeCall("src"))
+	assert.Equal(t, "dc0be356e8c2ba26e66448d97db76ad050206574\n", out.String())
+
+	out.Reset()
+	assert.NoError(t, verifyCall("dc0be356e8c2ba26e66448d97db76ad050206574", "src"))
+	assert.Equal(t, "dc0be356e8c2ba26e66448d97db76ad050206574\n", out.String())
+
+	writeFile(t, filepath.Join(dir, "src", "world"), "hello2")
+	out.Reset()
+	err := verifyCall("dc0be356e8c2ba26e66448d97db76ad050206574", "src")
+	assert.True(t, errors.Is(err, omnibor.ErrIdentityMismatch))
+	assert.Equal(t, "- blob 04fea06420ca60892f73becee3614f6d023a4b7f\n"+
+		"+ blob 23294b0610492cf55c1c4835216f20d376a287dd\n", out.String())
+
+	// without the recorded document only the mismatch is reported
+	out.Reset()
+	err = verifyCall("--output", filepath.Join(dir, "empty"), "dc0be356e8c2ba26e66448d97db76ad050206574", "src")
+	assert.True(t, errors.Is(err, omnibor.ErrIdentityMismatch))
+	assert.Equal(t, "", out.String())
+
+	err = verifyCall("dc0be356", "src")
+	assert.True(t, errors.Is(err, omnibor.ErrInvalidHashLength))
+}
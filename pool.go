@@ -0,0 +1,28 @@
+package omnibor
+
+// HasherPool bounds the number of objects hashed concurrently across every ingestion sharing it.
+// A server building many trees at once can hand the same pool to each of them, see WithSharedHasher,
+// instead of letting every tree spin up its own set of workers.
+type HasherPool struct {
+	slots chan struct{}
+}
+
+// NewHasherPool creates a HasherPool allowing at most size concurrent hashing operations.
+// A size below one defaults to the smaller of GOMAXPROCS and the number of CPUs.
+func NewHasherPool(size int) *HasherPool {
+	if size < 1 {
+		size = defaultWorkers()
+	}
+	return &HasherPool{
+		slots: make(chan struct{}, size),
+	}
+}
+
+// Do runs fn once a slot is available in the pool and releases the slot when fn returns.
+func (p *HasherPool) Do(fn func() error) error {
+	p.slots <- struct{}{}
+	defer func() {
+		<-p.slots
+	}()
+	return fn()
+}
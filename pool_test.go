@@ -0,0 +1,66 @@
+package omnibor
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHasherPoolBoundsConcurrency(t *testing.T) {
+	pool := NewHasherPool(2)
+
+	var running, peak int32
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = pool.Do(func() error {
+				n := atomic.AddInt32(&running, 1)
+				for {
+					p := atomic.LoadInt32(&peak)
+					if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+						break
+					}
+				}
+				time.Sleep(time.Millisecond)
+				atomic.AddInt32(&running, -1)
+				return nil
+			})
+		}()
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, peak, int32(2))
+}
+
+func TestSharedHasherAcrossTrees(t *testing.T) {
+	root1 := createTree(t)
+	root2 := t.TempDir()
+	writeTestFile(t, root2, "hello", "hello")
+	writeTestFile(t, root2, "world", "world")
+
+	pool := NewHasherPool(2)
+	gb1 := NewSha1OmniBOR()
+	gb2 := NewSha256OmniBOR()
+
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		assert.NoError(t, gb1.AddTree(root1, WithSharedHasher(pool)))
+	}()
+	go func() {
+		defer wg.Done()
+		assert.NoError(t, gb2.AddTree(root2, WithSharedHasher(pool)))
+	}()
+	wg.Wait()
+
+	expected1 := NewSha1OmniBOR()
+	assert.NoError(t, expected1.AddTree(root1))
+	assert.Equal(t, expected1.Identity(), gb1.Identity())
+	assert.Equal(t, "e32e7e7761709be17ef573556a82960d489ddf0092424f7db1c91d8363dde822", gb2.Identity())
+}
@@ -0,0 +1,51 @@
+package omnibor
+
+import (
+	"strings"
+)
+
+// RenderOption changes how Render lays out the references of a document.
+type RenderOption func(*renderOptions)
+
+type renderOptions struct {
+	terminator string
+	trailing   bool
+}
+
+// WithLineTerminator ends every reference line with terminator instead of "\n".
+func WithLineTerminator(terminator string) RenderOption {
+	return func(o *renderOptions) {
+		o.terminator = terminator
+	}
+}
+
+// WithoutTrailingTerminator omits the terminator after the last reference, so terminators only separate references.
+func WithoutTrailingTerminator() RenderOption {
+	return func(o *renderOptions) {
+		o.trailing = false
+	}
+}
+
+func (srv *omniBor) Render(opts ...RenderOption) string {
+	o := &renderOptions{
+		terminator: "\n",
+		trailing:   true,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	srv.lock.Lock()
+	refs := srv.sortedReferences()
+	srv.lock.Unlock()
+
+	lines := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		lines = append(lines, strings.TrimSuffix(ref.String(), "\n"))
+	}
+	doc := strings.Join(lines, o.terminator)
+	if o.trailing && len(lines) > 0 {
+		doc += o.terminator
+	}
+	return doc
+}
@@ -0,0 +1,31 @@
+package omnibor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender(t *testing.T) {
+	bom, err := NewIdentifier("dc0be356e8c2ba26e66448d97db76ad050206574")
+	assert.NoError(t, err)
+
+	gb := NewSha1OmniBOR()
+	assert.NoError(t, gb.AddReference([]byte("hello"), nil))
+	assert.NoError(t, gb.AddReference([]byte("hello2"), bom))
+
+	assert.Equal(t, gb.String(), gb.Render())
+
+	trimmed := gb.Render(WithoutTrailingTerminator())
+	assert.Equal(t, "blob 23294b0610492cf55c1c4835216f20d376a287dd bom dc0be356e8c2ba26e66448d97db76ad050206574\n"+
+		"blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0", trimmed)
+	assert.Equal(t, len(gb.String())-1, len(trimmed))
+
+	crlf := gb.Render(WithLineTerminator("\r\n"))
+	assert.Equal(t, len(gb.String())+2, len(crlf))
+
+	assert.Equal(t, "blob 23294b0610492cf55c1c4835216f20d376a287dd bom dc0be356e8c2ba26e66448d97db76ad050206574;"+
+		"blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0", gb.Render(WithLineTerminator(";"), WithoutTrailingTerminator()))
+
+	assert.Equal(t, "", NewSha1OmniBOR().Render(WithLineTerminator("\r\n")))
+}
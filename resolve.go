@@ -0,0 +1,62 @@
+package omnibor
+
+import (
+	"fmt"
+)
+
+// ResolvedTree is an artifact tree together with the documents its bom links lead to, loaded from a store
+// and resolved in turn, so the whole build graph below the tree can be walked without further lookups.
+type ResolvedTree struct {
+	ArtifactTree
+
+	// Boms holds the resolved tree of every bom link of the tree, keyed by bom identity.
+	// A document linked from several places is resolved once and shared.
+	Boms map[string]*ResolvedTree
+}
+
+func (srv *omniBor) Resolve(store ObjectStore) (*ResolvedTree, error) {
+	r := &resolver{
+		store:    store,
+		resolved: make(map[string]*ResolvedTree),
+		onPath:   make(map[string]bool),
+	}
+	return r.resolve(srv.Identity(), srv)
+}
+
+// resolver loads the documents of bom links once each, remembering those on the current path to detect cycles.
+type resolver struct {
+	store    ObjectStore
+	resolved map[string]*ResolvedTree
+	onPath   map[string]bool
+}
+
+func (r *resolver) resolve(identity string, tree ArtifactTree) (*ResolvedTree, error) {
+	r.onPath[identity] = true
+	defer delete(r.onPath, identity)
+
+	result := &ResolvedTree{
+		ArtifactTree: tree,
+		Boms:         make(map[string]*ResolvedTree),
+	}
+	for _, ref := range tree.LinkedReferences() {
+		bom := ref.Bom().Identity()
+		if r.onPath[bom] {
+			return nil, fmt.Errorf("%w: %s links back to %s", ErrCycle, ref.Identity(), bom)
+		}
+		if sub, ok := r.resolved[bom]; ok {
+			result.Boms[bom] = sub
+			continue
+		}
+		subTree, err := loadTree(r.store, bom)
+		if err != nil {
+			return nil, fmt.Errorf("bom of %s: %w", ref.Identity(), err)
+		}
+		sub, err := r.resolve(bom, subTree)
+		if err != nil {
+			return nil, err
+		}
+		r.resolved[bom] = sub
+		result.Boms[bom] = sub
+	}
+	return result, nil
+}
@@ -0,0 +1,45 @@
+package omnibor
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolve(t *testing.T) {
+	store := NewMemoryStore()
+	top, leaf := storeTwoLevelTree(t, store)
+
+	resolved, err := top.Resolve(store)
+	require.NoError(t, err)
+	assert.Equal(t, top.Identity(), resolved.Identity())
+	require.Len(t, resolved.Boms, 1)
+	sub := resolved.Boms[leaf.Identity()]
+	require.NotNil(t, sub)
+	assert.Equal(t, leaf.String(), sub.String())
+	assert.Empty(t, sub.Boms)
+
+	// the same sub-document linked twice is loaded once
+	bom, err := NewIdentifier(leaf.Identity())
+	require.NoError(t, err)
+	require.NoError(t, top.AddReference([]byte("world"), bom))
+	resolved, err = top.Resolve(store)
+	require.NoError(t, err)
+	assert.Len(t, resolved.Boms, 1)
+
+	_, err = top.Resolve(NewMemoryStore())
+	assert.True(t, errors.Is(err, ErrObjectNotFound))
+}
+
+func TestResolveCycle(t *testing.T) {
+	// a document cannot link to itself through hashes, a store serving the wrong content can
+	store := NewMemoryStore()
+	looping := NewSha1OmniBOR()
+	require.NoError(t, looping.AddReference([]byte("hello"), &identifier{identity: "dc0be356e8c2ba26e66448d97db76ad050206574"}))
+	require.NoError(t, store.Put("dc0be356e8c2ba26e66448d97db76ad050206574", []byte(looping.String())))
+
+	_, err := looping.Resolve(store)
+	assert.True(t, errors.Is(err, ErrCycle))
+}
@@ -0,0 +1,149 @@
+/*
+Package spdx exports OmniBOR artifact trees as SPDX 2.3 JSON documents.
+
+Every reference of the tree becomes a file element named after its gitoid URI. SPDX 2.3 has no gitoid
+checksum algorithm, so the gitoid is recorded under the algorithm it was computed with: sha1 gitoids as
+SHA1 checksums and sha256 gitoids as SHA256 checksums. Note that a gitoid hashes the git blob header
+followed by the content, so these checksums do not equal the plain digest of the file.
+
+A reference built from another artifact tree gets a GENERATED_FROM relationship to a file element standing for
+that tree's OmniBOR document. The document namespace is the gitoid URI of the tree itself.
+*/
+package spdx
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	omnibor "github.com/omnibor/omnibor-go"
+)
+
+const (
+	spdxVersion = "SPDX-2.3"
+	dataLicense = "CC0-1.0"
+	documentID  = "SPDXRef-DOCUMENT"
+	creator     = "Tool: omnibor-go"
+)
+
+// now is replaced by tests to get reproducible creation timestamps.
+var now = time.Now
+
+type document struct {
+	SPDXVersion       string         `json:"spdxVersion"`
+	DataLicense       string         `json:"dataLicense"`
+	SPDXID            string         `json:"SPDXID"`
+	Name              string         `json:"name"`
+	DocumentNamespace string         `json:"documentNamespace"`
+	CreationInfo      creationInfo   `json:"creationInfo"`
+	Files             []file         `json:"files"`
+	Relationships     []relationship `json:"relationships"`
+}
+
+type creationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type file struct {
+	SPDXID    string     `json:"SPDXID"`
+	FileName  string     `json:"fileName"`
+	Checksums []checksum `json:"checksums"`
+}
+
+type checksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+type relationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+// ToSPDX renders tree as an indented SPDX 2.3 JSON document.
+func ToSPDX(tree omnibor.ArtifactTree) ([]byte, error) {
+	identity := tree.Identity()
+	treeURI, _, err := gitoid(identity)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := document{
+		SPDXVersion:       spdxVersion,
+		DataLicense:       dataLicense,
+		SPDXID:            documentID,
+		Name:              treeURI,
+		DocumentNamespace: treeURI,
+		CreationInfo: creationInfo{
+			Created:  now().UTC().Format(time.RFC3339),
+			Creators: []string{creator},
+		},
+		Files:         make([]file, 0),
+		Relationships: make([]relationship, 0),
+	}
+
+	boms := make(map[string]bool)
+	for _, ref := range tree.References() {
+		f, err := newFile(ref.Identity())
+		if err != nil {
+			return nil, err
+		}
+		doc.Files = append(doc.Files, f)
+		doc.Relationships = append(doc.Relationships, relationship{
+			SPDXElementID:      documentID,
+			RelationshipType:   "DESCRIBES",
+			RelatedSPDXElement: f.SPDXID,
+		})
+
+		if ref.Bom() == nil {
+			continue
+		}
+		bom, err := newFile(ref.Bom().Identity())
+		if err != nil {
+			return nil, err
+		}
+		if !boms[bom.SPDXID] {
+			boms[bom.SPDXID] = true
+			doc.Files = append(doc.Files, bom)
+		}
+		doc.Relationships = append(doc.Relationships, relationship{
+			SPDXElementID:      f.SPDXID,
+			RelationshipType:   "GENERATED_FROM",
+			RelatedSPDXElement: bom.SPDXID,
+		})
+	}
+
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+func newFile(identity string) (file, error) {
+	uri, algorithm, err := gitoid(identity)
+	if err != nil {
+		return file{}, err
+	}
+	return file{
+		SPDXID:   "SPDXRef-File-" + identity,
+		FileName: uri,
+		Checksums: []checksum{
+			{Algorithm: algorithm, ChecksumValue: identity},
+		},
+	}, nil
+}
+
+// gitoid returns the gitoid URI of identity and the SPDX checksum algorithm it maps to.
+func gitoid(identity string) (string, string, error) {
+	switch len(identity) {
+	case 40:
+		return "gitoid:blob:sha1:" + identity, "SHA1", nil
+	case 64:
+		return "gitoid:blob:sha256:" + identity, "SHA256", nil
+	default:
+		return "", "", fmt.Errorf("%w: %d", omnibor.ErrInvalidHashLength, len(identity))
+	}
+}
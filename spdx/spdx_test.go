@@ -0,0 +1,55 @@
+package spdx
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	omnibor "github.com/omnibor/omnibor-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestToSPDX(t *testing.T) {
+	now = func() time.Time {
+		return time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	}
+	defer func() {
+		now = time.Now
+	}()
+
+	leaf := omnibor.NewSha256OmniBOR()
+	require.NoError(t, leaf.AddReference([]byte("hello"), nil))
+	require.NoError(t, leaf.AddReference([]byte("world"), nil))
+
+	tree := omnibor.NewSha1OmniBOR()
+	require.NoError(t, tree.AddReference([]byte("hello2"), leaf))
+	require.NoError(t, tree.AddReference([]byte("independent"), nil))
+
+	out, err := ToSPDX(tree)
+	require.NoError(t, err)
+
+	golden := filepath.Join("testdata", "nested.spdx.json")
+	if *update {
+		require.NoError(t, ioutil.WriteFile(golden, out, 0644))
+	}
+	expected, err := ioutil.ReadFile(golden)
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), string(out))
+
+	var doc document
+	require.NoError(t, json.Unmarshal(out, &doc))
+	assert.Len(t, doc.Files, 3)
+	assert.Equal(t, "SHA1", doc.Files[0].Checksums[0].Algorithm)
+	assert.Equal(t, "SHA256", doc.Files[1].Checksums[0].Algorithm)
+	assert.Equal(t, relationship{
+		SPDXElementID:      "SPDXRef-File-23294b0610492cf55c1c4835216f20d376a287dd",
+		RelationshipType:   "GENERATED_FROM",
+		RelatedSPDXElement: "SPDXRef-File-" + leaf.Identity(),
+	}, doc.Relationships[1])
+}
@@ -0,0 +1,46 @@
+package omnibor
+
+import (
+	"fmt"
+)
+
+// TreeStats summarizes the size of an ArtifactTree.
+type TreeStats struct {
+	// ReferenceCount is the number of references in the tree.
+	ReferenceCount int
+
+	// LinkedCount is the number of references carrying a bom link.
+	LinkedCount int
+
+	// DocumentBytes is the length of the document returned by String, which is what gets persisted.
+	DocumentBytes int
+}
+
+func (srv *omniBor) Stats() TreeStats {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	stats := TreeStats{
+		ReferenceCount: len(srv.gitRefs),
+	}
+	for _, ref := range srv.gitRefs {
+		// "blob <identity>\n"
+		stats.DocumentBytes += len(ref.Kind()) + len(" ") + len(ref.Identity()) + len("\n")
+		if r, ok := ref.(reference); ok && r.annotations != nil {
+			stats.DocumentBytes += len(renderAnnotations(r.annotations))
+		}
+		if ref.Bom() != nil {
+			// " bom <identity>"
+			stats.LinkedCount++
+			stats.DocumentBytes += len(" bom ") + len(ref.Bom().Identity())
+		}
+	}
+	return stats
+}
+
+// summaryIdentityLength is the number of identity digits shown by Summary, as many as git shows for abbreviated hashes.
+const summaryIdentityLength = 12
+
+func (srv *omniBor) Summary() string {
+	return fmt.Sprintf("OmniBOR(%s, %d refs, id=%s...)", srv.hashType, srv.Len(), srv.Identity()[:summaryIdentityLength])
+}
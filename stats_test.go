@@ -0,0 +1,40 @@
+package omnibor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStats(t *testing.T) {
+	gb := NewSha1OmniBOR()
+	assert.Equal(t, TreeStats{}, gb.Stats())
+
+	leaf := NewSha256OmniBOR()
+	assert.NoError(t, leaf.AddReference([]byte("hello"), nil))
+
+	assert.NoError(t, gb.AddReference([]byte("hello"), nil))
+	assert.NoError(t, gb.AddReference([]byte("world"), nil))
+	assert.NoError(t, gb.AddReference([]byte("hello2"), leaf))
+
+	assert.Equal(t, TreeStats{
+		ReferenceCount: 3,
+		LinkedCount:    1,
+		DocumentBytes:  3*46 + 69,
+	}, gb.Stats())
+	assert.Equal(t, len(gb.String()), gb.Stats().DocumentBytes)
+}
+
+func TestSummary(t *testing.T) {
+	gb := NewSha1OmniBOR()
+	assert.Equal(t, "OmniBOR(sha1, 0 refs, id=e69de29bb2d1...)", gb.Summary())
+
+	assert.NoError(t, gb.AddReference([]byte("hello"), nil))
+	assert.NoError(t, gb.AddReference([]byte("world"), nil))
+	assert.Equal(t, "OmniBOR(sha1, 2 refs, id=dc0be356e8c2...)", gb.Summary())
+
+	gb = NewSha256OmniBOR()
+	assert.NoError(t, gb.AddReference([]byte("hello"), nil))
+	assert.NoError(t, gb.AddReference([]byte("world"), nil))
+	assert.Equal(t, "OmniBOR(sha256, 2 refs, id=e32e7e776170...)", gb.Summary())
+}
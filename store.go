@@ -0,0 +1,238 @@
+package omnibor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ObjectStore persists OmniBOR documents keyed by their identity.
+// The stores of this package accept identities qualified with their algorithm, as returned by
+// Reference.QualifiedIdentity, and store them under the bare digest.
+type ObjectStore interface {
+	// Put stores content under identity, replacing any existing object.
+	Put(identity string, content []byte) error
+
+	// Get returns the content stored under identity.
+	// It returns an error wrapping ErrObjectNotFound if there is no such object.
+	Get(identity string) ([]byte, error)
+
+	// Has reports whether an object is stored under identity.
+	Has(identity string) bool
+}
+
+// KeyLister is implemented by ObjectStores that can list their objects, as the stores of this package do.
+// VerifyStore requires it.
+type KeyLister interface {
+	// Keys returns the identities of every stored object in ascending order.
+	Keys() ([]string, error)
+}
+
+// FileObjectStore is an ObjectStore keeping every object in its own file below a directory,
+// using the same layout as git: <dir>/object/<first two hex digits>/<remaining hex digits>.
+type FileObjectStore struct {
+	dir          string
+	verifyOnRead bool
+}
+
+// StoreOption configures a FileObjectStore.
+type StoreOption func(*FileObjectStore)
+
+// WithVerifyOnRead makes Get recompute the identity of every object it reads
+// and fail with ErrIdentityMismatch when the content does not match the path it was read from.
+func WithVerifyOnRead() StoreOption {
+	return func(s *FileObjectStore) {
+		s.verifyOnRead = true
+	}
+}
+
+// NewFileObjectStore creates a FileObjectStore rooted at dir.
+// The directory is created on the first Put.
+func NewFileObjectStore(dir string, opts ...StoreOption) *FileObjectStore {
+	s := &FileObjectStore{
+		dir: dir,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *FileObjectStore) path(identity string) (string, error) {
+	key, err := objectKey(identity)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.dir, "object", key[0:2], key[2:]), nil
+}
+
+// objectKey returns the hex digest an object with the given identity is stored under.
+// identity is either the digest itself or the digest qualified with its algorithm, as returned by
+// Reference.QualifiedIdentity. Combined identities such as "sha1+sha256:..." name two objects and are rejected.
+func objectKey(identity string) (string, error) {
+	key := identity
+	if i := strings.IndexByte(identity, ':'); i >= 0 {
+		algo, digest := HashAlgorithm(identity[:i]), identity[i+1:]
+		if strings.Contains(string(algo), "+") {
+			return "", fmt.Errorf("%w: %q combines several algorithms, store each identity separately", ErrUnknownAlgorithm, algo)
+		}
+		if algo.HexLength() == 0 {
+			return "", fmt.Errorf("%w: %q", ErrUnknownAlgorithm, algo)
+		}
+		if len(digest) != algo.HexLength() {
+			return "", fmt.Errorf("%w: %s identity of length %d", ErrAlgorithmMismatch, algo, len(digest))
+		}
+		key = digest
+	}
+	if _, err := newTreeForLength(len(key)); err != nil {
+		return "", err
+	}
+	if err := validateHex(key); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// ObjectPath returns the path of the file the object with the given identity is stored in, whether or not it exists.
+func (s *FileObjectStore) ObjectPath(identity string) (string, error) {
+	return s.path(identity)
+}
+
+func (s *FileObjectStore) Put(identity string, content []byte) error {
+	objectPath, err := s.path(identity)
+	if err != nil {
+		return err
+	}
+
+	// the object is renamed into place once written, so a crash never leaves a truncated object behind
+	temp, err := writeTemp(objectPath, content)
+	if err != nil {
+		return err
+	}
+	if err := rename(temp, objectPath); err != nil {
+		os.Remove(temp)
+		return err
+	}
+	return nil
+}
+
+func (s *FileObjectStore) Get(identity string) ([]byte, error) {
+	objectPath, err := s.path(identity)
+	if err != nil {
+		return nil, err
+	}
+	content, err := ioutil.ReadFile(objectPath)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%s: %w", identity, ErrObjectNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if s.verifyOnRead {
+		if err := verifyObject(identity, content); err != nil {
+			return nil, err
+		}
+	}
+	return content, nil
+}
+
+func (s *FileObjectStore) Has(identity string) bool {
+	objectPath, err := s.path(identity)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(objectPath)
+	return err == nil
+}
+
+// Keys returns the identities of every stored object in ascending order.
+// Files below the object directory that are not named after an identity, such as the temporary files
+// of an interrupted Batch, are ignored.
+func (s *FileObjectStore) Keys() ([]string, error) {
+	var keys []string
+	err := s.walkKeys(func(identity string) error {
+		keys = append(keys, identity)
+		return nil
+	})
+	return keys, err
+}
+
+// walkKeys calls fn with the identity of every stored object in ascending order, as found on disk.
+func (s *FileObjectStore) walkKeys(fn func(identity string) error) error {
+	root := filepath.Join(s.dir, "object")
+	if _, err := os.Stat(root); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		prefix, rest, ok := strings.Cut(filepath.ToSlash(rel), "/")
+		if !ok || len(prefix) != 2 {
+			return nil
+		}
+		key, err := objectKey(prefix + rest)
+		if err != nil {
+			return nil
+		}
+		return fn(key)
+	})
+}
+
+// verifyObject checks that content hashes to identity, picking the algorithm from the identity's length.
+// Compressed objects are checked against the identity of the document they hold.
+func verifyObject(identity string, content []byte) error {
+	identity, err := objectKey(identity)
+	if err != nil {
+		return err
+	}
+	gb, err := newTreeForLength(len(identity))
+	if err != nil {
+		return err
+	}
+	content, err = decompressObject(content)
+	if err != nil {
+		return fmt.Errorf("%s: %w", identity, err)
+	}
+
+	actual, err := gb.hash(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return err
+	}
+	if actual != identity {
+		return fmt.Errorf("%s: %w: content hashes to %s", identity, ErrIdentityMismatch, actual)
+	}
+	return nil
+}
+
+// LoadTree parses the document stored under identity, plain or compressed by WriteCompressed, back into a tree
+// that can be added to and stored again. Any change gives the tree a new identity, so the changed document is
+// stored under that new identity and the original stays in place, as other documents may link to it.
+// An error wrapping ErrIdentityMismatch is returned if the document does not have the identity it is stored under.
+func LoadTree(store ObjectStore, identity string) (ArtifactTree, error) {
+	tree, err := loadTree(store, identity)
+	if err != nil {
+		return nil, err
+	}
+	key, err := objectKey(identity)
+	if err != nil {
+		return nil, err
+	}
+	if tree.Identity() != key {
+		return nil, fmt.Errorf("%s: %w: document hashes to %s", identity, ErrIdentityMismatch, tree.Identity())
+	}
+	return tree, nil
+}
@@ -0,0 +1,202 @@
+package omnibor
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileObjectStore(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileObjectStore(dir)
+
+	gb := NewSha1OmniBOR()
+	assert.NoError(t, gb.AddReference([]byte("hello"), nil))
+	assert.NoError(t, gb.AddReference([]byte("world"), nil))
+
+	assert.False(t, store.Has(gb.Identity()))
+	assert.NoError(t, store.Put(gb.Identity(), []byte(gb.String())))
+	assert.True(t, store.Has(gb.Identity()))
+	assert.FileExists(t, filepath.Join(dir, "object", "dc", "0be356e8c2ba26e66448d97db76ad050206574"))
+
+	content, err := store.Get(gb.Identity())
+	assert.NoError(t, err)
+	assert.Equal(t, gb.String(), string(content))
+
+	_, err = store.Get("04fea06420ca60892f73becee3614f6d023a4b7f")
+	assert.True(t, errors.Is(err, ErrObjectNotFound))
+}
+
+func TestFileObjectStorePutCrash(t *testing.T) {
+	errCrash := errors.New("crash")
+	defer func() {
+		rename = os.Rename
+	}()
+	rename = func(from, to string) error {
+		return errCrash
+	}
+
+	dir := t.TempDir()
+	store := NewFileObjectStore(dir)
+	err := store.Put("dc0be356e8c2ba26e66448d97db76ad050206574", []byte("blob 04fea06420ca60892f73becee3614f6d023a4b7f\n"))
+	assert.True(t, errors.Is(err, errCrash))
+
+	// neither a partial object nor the temporary file is left behind
+	assert.False(t, store.Has("dc0be356e8c2ba26e66448d97db76ad050206574"))
+	assert.Empty(t, listFiles(t, dir))
+}
+
+func TestFileObjectStoreVerifyOnRead(t *testing.T) {
+	dir := t.TempDir()
+
+	gb := NewSha256OmniBOR()
+	assert.NoError(t, gb.AddReference([]byte("hello"), nil))
+	assert.NoError(t, gb.AddReference([]byte("world"), nil))
+	identity := gb.Identity()
+
+	store := NewFileObjectStore(dir, WithVerifyOnRead())
+	assert.NoError(t, store.Put(identity, []byte(gb.String())))
+
+	content, err := store.Get(identity)
+	assert.NoError(t, err)
+	assert.Equal(t, gb.String(), string(content))
+
+	objectPath := filepath.Join(dir, "object", identity[:2], identity[2:])
+	corrupted := []byte(gb.String())
+	corrupted[10] = 'f'
+	require.NoError(t, os.WriteFile(objectPath, corrupted, 0644))
+
+	_, err = store.Get(identity)
+	assert.True(t, errors.Is(err, ErrIdentityMismatch))
+
+	// without verification the corrupted bytes are returned as is
+	content, err = NewFileObjectStore(dir).Get(identity)
+	assert.NoError(t, err)
+	assert.Equal(t, corrupted, content)
+}
+
+func TestObjectStoreQualifiedIdentity(t *testing.T) {
+	sha1Tree := NewSha1OmniBOR()
+	assert.NoError(t, sha1Tree.AddReference([]byte("hello"), nil))
+	sha256Tree := NewSha256OmniBOR()
+	assert.NoError(t, sha256Tree.AddReference([]byte("hello"), nil))
+
+	dir := t.TempDir()
+	for _, store := range []ObjectStore{NewFileObjectStore(dir, WithVerifyOnRead()), NewMemoryStore()} {
+		for algo, gb := range map[HashAlgorithm]ArtifactTree{Sha1: sha1Tree, Sha256: sha256Tree} {
+			qualified := string(algo) + ":" + gb.Identity()
+			require.NoError(t, store.Put(qualified, []byte(gb.String())))
+			assert.True(t, store.Has(gb.Identity()))
+			assert.True(t, store.Has(qualified))
+
+			content, err := store.Get(gb.Identity())
+			require.NoError(t, err)
+			assert.Equal(t, gb.String(), string(content))
+			content, err = store.Get(qualified)
+			require.NoError(t, err)
+			assert.Equal(t, gb.String(), string(content))
+		}
+
+		combined := "sha1+sha256:" + sha1Tree.Identity() + sha256Tree.Identity()
+		err := store.Put(combined, []byte(sha1Tree.String()))
+		assert.True(t, errors.Is(err, ErrUnknownAlgorithm))
+		assert.Contains(t, err.Error(), "combines several algorithms")
+		assert.False(t, store.Has(combined))
+
+		err = store.Put("sha256:"+sha1Tree.Identity(), []byte(sha1Tree.String()))
+		assert.True(t, errors.Is(err, ErrAlgorithmMismatch))
+		err = store.Put("md5:"+sha1Tree.Identity(), []byte(sha1Tree.String()))
+		assert.True(t, errors.Is(err, ErrUnknownAlgorithm))
+	}
+	assert.FileExists(t, filepath.Join(dir, "object", sha1Tree.Identity()[:2], sha1Tree.Identity()[2:]))
+	assert.FileExists(t, filepath.Join(dir, "object", sha256Tree.Identity()[:2], sha256Tree.Identity()[2:]))
+}
+
+func TestLoadTree(t *testing.T) {
+	store := NewFileObjectStore(t.TempDir())
+
+	gb := NewSha1OmniBOR()
+	require.NoError(t, gb.AddReference([]byte("hello"), nil))
+	require.NoError(t, gb.AddReference([]byte("world"), nil))
+	require.NoError(t, store.Put(gb.Identity(), []byte(gb.String())))
+
+	loaded, err := LoadTree(store, gb.Identity())
+	require.NoError(t, err)
+	assert.Equal(t, gb.String(), loaded.String())
+
+	// the changed document is stored under its new identity next to the original
+	require.NoError(t, loaded.AddReference([]byte("hello2"), nil))
+	assert.NotEqual(t, gb.Identity(), loaded.Identity())
+	require.NoError(t, store.Put(loaded.Identity(), []byte(loaded.String())))
+
+	reloaded, err := LoadTree(store, "sha1:"+loaded.Identity())
+	require.NoError(t, err)
+	assert.Equal(t, 3, reloaded.Len())
+	original, err := LoadTree(store, gb.Identity())
+	require.NoError(t, err)
+	assert.Equal(t, 2, original.Len())
+
+	_, err = LoadTree(store, "04fea06420ca60892f73becee3614f6d023a4b7f")
+	assert.True(t, errors.Is(err, ErrObjectNotFound))
+
+	require.NoError(t, store.Put("04fea06420ca60892f73becee3614f6d023a4b7f", []byte(gb.String())))
+	_, err = LoadTree(store, "04fea06420ca60892f73becee3614f6d023a4b7f")
+	assert.True(t, errors.Is(err, ErrIdentityMismatch))
+}
+
+func TestLoadTreeDuplicateContent(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "a", "hello")
+	writeTestFile(t, root, "b", "hello")
+	writeTestFile(t, root, "c", "world")
+
+	gb := NewSha1OmniBOR()
+	require.NoError(t, gb.AddTree(root))
+	assert.Equal(t, "dc0be356e8c2ba26e66448d97db76ad050206574", gb.Identity())
+
+	store := NewFileObjectStore(t.TempDir())
+	require.NoError(t, store.Put(gb.Identity(), []byte(gb.String())))
+
+	loaded, err := LoadTree(store, gb.Identity())
+	require.NoError(t, err)
+	assert.Equal(t, gb.String(), loaded.String())
+
+	strict, err := ParseStrict(strings.NewReader(gb.String()))
+	require.NoError(t, err)
+	assert.Equal(t, gb.Identity(), strict.Identity())
+}
+
+func TestLoadTreeBomPerIdentity(t *testing.T) {
+	b1, err := NewIdentifier("dc0be356e8c2ba26e66448d97db76ad050206574")
+	require.NoError(t, err)
+
+	gb := NewSha1OmniBOR()
+	require.NoError(t, gb.AddReference([]byte("hello"), nil))
+	require.NoError(t, gb.AddReference([]byte("hello"), b1))
+	require.NoError(t, gb.AddReference([]byte("world"), nil))
+	require.Equal(t, 3, gb.Len())
+
+	parsed, err := Parse(strings.NewReader(gb.String()))
+	require.NoError(t, err)
+	assert.Equal(t, gb.String(), parsed.String())
+	assert.Equal(t, gb.Identity(), parsed.Identity())
+	strict, err := ParseStrict(strings.NewReader(gb.String()))
+	require.NoError(t, err)
+	assert.Equal(t, gb.Identity(), strict.Identity())
+
+	fromJSON, err := ParseJSON(bytes.NewReader(gb.CanonicalJSON()))
+	require.NoError(t, err)
+	assert.Equal(t, gb.Identity(), fromJSON.Identity())
+
+	store := NewFileObjectStore(t.TempDir())
+	require.NoError(t, store.Put(gb.Identity(), []byte(gb.String())))
+	loaded, err := LoadTree(store, gb.Identity())
+	require.NoError(t, err)
+	assert.Equal(t, gb.String(), loaded.String())
+}
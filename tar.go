@@ -0,0 +1,97 @@
+package omnibor
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+	"path"
+	"strings"
+)
+
+// AddTar reads a tar archive from r and adds a reference for every regular file it holds, using the entry's
+// size as the content length, so an archive yields the same references as AddTree on its extracted contents.
+// The archive is read in a single pass and entries are hashed one at a time as they are reached, so WithWorkers
+// has no effect. Directories and special files are skipped and WithExclude is matched against entry names.
+// Sparse files are hashed with their holes filled with zeros, as they would be extracted.
+// A hard link, or a symbolic link unless disabled by WithFollowSymlinks, adds the reference of the regular file
+// it points to if that file came earlier in the archive and is skipped otherwise. A symbolic link that is not
+// followed is referenced like git records it, as a blob holding the link's target path.
+func (srv *omniBor) AddTar(r io.Reader, opts ...Option) error {
+	o := srv.ingestOptions(opts)
+	o.workers = 0
+
+	tr := tar.NewReader(r)
+	var hdr *tar.Header
+	identities := make(map[string]string)
+	walk := func(fn func(fileEvent) error) error {
+		for {
+			var err error
+			if hdr, err = tr.Next(); err == io.EOF {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			if excluded, err := o.excludedEntry(hdr.Name); err != nil {
+				return err
+			} else if excluded {
+				continue
+			}
+			switch hdr.Typeflag {
+			case tar.TypeReg, tar.TypeRegA, tar.TypeGNUSparse, tar.TypeLink, tar.TypeSymlink:
+				if err := fn(fileEvent{path: hdr.Name, name: hdr.Name, info: hdr.FileInfo()}); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	add := func(ctx context.Context, ev fileEvent) (string, int64, error) {
+		identity, size, err := srv.addTarEntry(o, tr, hdr, identities)
+		if err != nil {
+			return "", 0, withPath(err, hdr.Name)
+		}
+		if identity != "" {
+			identities[path.Clean(hdr.Name)] = identity
+		}
+		return identity, size, nil
+	}
+	return srv.addFiles(context.Background(), o, walk, add)
+}
+
+// addTarEntry adds a reference for the entry of tr described by hdr, returning its gitoid and the number of bytes
+// hashed. The gitoid is empty if hdr is a link to a file not in identities, the gitoids of earlier entries by name.
+func (srv *omniBor) addTarEntry(o *options, tr *tar.Reader, hdr *tar.Header, identities map[string]string) (string, int64, error) {
+	var target string
+	switch hdr.Typeflag {
+	case tar.TypeLink:
+		target = path.Clean(hdr.Linkname)
+	case tar.TypeSymlink:
+		if !o.followSymlinks {
+			size := int64(len(hdr.Linkname))
+			identity, err := srv.addFileID(o, strings.NewReader(hdr.Linkname), size)
+			return identity, size, err
+		}
+		target = path.Join(path.Dir(hdr.Name), hdr.Linkname)
+	default:
+		identity, err := srv.addFileID(o, tr, hdr.Size)
+		return identity, hdr.Size, err
+	}
+
+	identity, ok := identities[target]
+	if !ok {
+		return "", 0, nil
+	}
+	srv.lock.Lock()
+	srv.appendDistinct(reference{identity: identity})
+	srv.lock.Unlock()
+	return identity, 0, nil
+}
+
+// excludedEntry reports whether the archive entry name, or a directory it is in, matches one of the exclude patterns.
+func (o *options) excludedEntry(name string) (bool, error) {
+	for name = strings.TrimPrefix(path.Clean(name), "/"); name != "." && name != ""; name = path.Dir(name) {
+		if excluded, err := o.excluded(".", name); err != nil || excluded {
+			return excluded, err
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,90 @@
+package omnibor
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type tarEntry struct {
+	name     string
+	typeflag byte
+	content  string
+	linkname string
+}
+
+func buildTar(t *testing.T, entries ...tarEntry) *bytes.Buffer {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, entry := range entries {
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name:     entry.name,
+			Typeflag: entry.typeflag,
+			Size:     int64(len(entry.content)),
+			Linkname: entry.linkname,
+			Mode:     0644,
+		}))
+		_, err := tw.Write([]byte(entry.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	return &buf
+}
+
+func TestAddTar(t *testing.T) {
+	archive := buildTar(t,
+		tarEntry{name: "src/", typeflag: tar.TypeDir},
+		tarEntry{name: "src/a/hello", typeflag: tar.TypeReg, content: "hello"},
+		tarEntry{name: "src/b/world", typeflag: tar.TypeReg, content: "world"},
+		tarEntry{name: "src/vendor/hello2", typeflag: tar.TypeReg, content: "hello2"},
+		tarEntry{name: "src/fifo", typeflag: tar.TypeFifo},
+	).Bytes()
+
+	gb := NewSha1OmniBOR()
+	require.NoError(t, gb.AddTar(bytes.NewReader(archive), WithExclude("vendor")))
+	assert.Equal(t, "dc0be356e8c2ba26e66448d97db76ad050206574", gb.Identity())
+
+	gb = NewSha256OmniBOR()
+	require.NoError(t, gb.AddTar(bytes.NewReader(archive), WithExclude("src/vendor")))
+	assert.Equal(t, "e32e7e7761709be17ef573556a82960d489ddf0092424f7db1c91d8363dde822", gb.Identity())
+}
+
+func TestAddTarLinks(t *testing.T) {
+	archive := buildTar(t,
+		tarEntry{name: "hello", typeflag: tar.TypeReg, content: "hello"},
+		tarEntry{name: "dir/hard", typeflag: tar.TypeLink, linkname: "hello"},
+		tarEntry{name: "dir/soft", typeflag: tar.TypeSymlink, linkname: "../hello"},
+		tarEntry{name: "dangling", typeflag: tar.TypeSymlink, linkname: "missing"},
+	).Bytes()
+
+	var files []string
+	gb := NewSha1OmniBOR()
+	require.NoError(t, gb.AddTar(bytes.NewReader(archive), WithFileIdentities(func(path, identity string) {
+		files = append(files, path)
+	})))
+	// the links add the same content again, which is listed once
+	assert.Equal(t, "blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\n", gb.String())
+	assert.Equal(t, []string{"hello", "dir/hard", "dir/soft"}, files)
+
+	// links that are not followed hold their target path, as git records them
+	gb = NewSha1OmniBOR()
+	require.NoError(t, gb.AddTar(bytes.NewReader(archive), WithFollowSymlinks(false)))
+	expected := NewSha1OmniBOR()
+	for _, content := range []string{"hello", "hello", "../hello", "missing"} {
+		require.NoError(t, expected.AddReference([]byte(content), nil))
+	}
+	assert.Equal(t, expected.String(), gb.String())
+}
+
+func TestAddTarTruncated(t *testing.T) {
+	archive := buildTar(t, tarEntry{name: "hello", typeflag: tar.TypeReg, content: "hello"}).Bytes()
+
+	gb := NewSha1OmniBOR()
+	err := gb.AddTar(bytes.NewReader(archive[:514]))
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, errWalkCancelled))
+}
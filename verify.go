@@ -0,0 +1,78 @@
+package omnibor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Verify reports whether content hashes to expectedIdentity when its gitoid is computed with algo.
+// It is meant to check that a persisted OmniBOR document still matches the object name it is stored under.
+// content is read until io.EOF and buffered in memory, as the gitoid header needs its length.
+// An error is returned if content cannot be read, algo is unknown or expectedIdentity cannot have been computed with algo.
+func Verify(content io.Reader, expectedIdentity string, algo HashAlgorithm) (bool, error) {
+	gb, err := newTreeForAlgorithm(algo)
+	if err != nil {
+		return false, err
+	}
+	if len(expectedIdentity) != algo.HexLength() {
+		if other, err := newTreeForLength(len(expectedIdentity)); err == nil {
+			return false, fmt.Errorf("%w: %s identity verified as %s", ErrAlgorithmMismatch, other.hashType, algo)
+		}
+		return false, fmt.Errorf("%w: %d", ErrInvalidHashLength, len(expectedIdentity))
+	}
+
+	buf, err := io.ReadAll(content)
+	if err != nil {
+		return false, err
+	}
+	actual, err := gb.hash(bytes.NewReader(buf), int64(len(buf)))
+	if err != nil {
+		return false, err
+	}
+	return actual == expectedIdentity, nil
+}
+
+// VerifyStore recomputes the identity of every object of store computed with algo and returns the identities
+// of those whose content does not hash to the identity they are stored under, in ascending order.
+// Objects of the other algorithm are skipped. store must list its objects by implementing KeyLister,
+// as FileObjectStore and MemoryStore do.
+// An error is returned if algo is unknown, store cannot list its objects or an object cannot be read.
+func VerifyStore(store ObjectStore, algo HashAlgorithm) ([]string, error) {
+	if algo.HexLength() == 0 {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownAlgorithm, algo)
+	}
+
+	corrupted := []string{}
+	verify := func(identity string) error {
+		if len(identity) != algo.HexLength() {
+			return nil
+		}
+		content, err := store.Get(identity)
+		if err == nil {
+			err = verifyObject(identity, content)
+		} else if !errors.Is(err, ErrIdentityMismatch) {
+			return err
+		}
+		if err != nil {
+			corrupted = append(corrupted, identity)
+		}
+		return nil
+	}
+
+	lister, ok := store.(KeyLister)
+	if !ok {
+		return nil, fmt.Errorf("%T cannot list its objects, it does not implement KeyLister", store)
+	}
+	keys, err := lister.Keys()
+	if err != nil {
+		return nil, err
+	}
+	for _, identity := range keys {
+		if err := verify(identity); err != nil {
+			return nil, err
+		}
+	}
+	return corrupted, nil
+}
@@ -0,0 +1,86 @@
+package omnibor
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerify(t *testing.T) {
+	for _, gb := range []ArtifactTree{NewSha1OmniBOR(), NewSha256OmniBOR()} {
+		assert.NoError(t, gb.AddReference([]byte("hello"), nil))
+		assert.NoError(t, gb.AddReference([]byte("world"), nil))
+		algo := Sha1
+		if len(gb.Identity()) == 64 {
+			algo = Sha256
+		}
+
+		ok, err := Verify(strings.NewReader(gb.String()), gb.Identity(), algo)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	}
+}
+
+func TestVerifyTampered(t *testing.T) {
+	doc := "blob 04fea06420ca60892f73becee3614f6d023a4b7f\n" +
+		"blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\n"
+	tampered := strings.Replace(doc, "04fea0", "04fea1", 1)
+
+	ok, err := Verify(strings.NewReader(tampered), "dc0be356e8c2ba26e66448d97db76ad050206574", Sha1)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = Verify(strings.NewReader(doc), "dc0be356e8c2ba26e66448d97db76ad050206574", Sha256)
+	assert.True(t, errors.Is(err, ErrAlgorithmMismatch))
+
+	_, err = Verify(strings.NewReader(doc), "dc0be356", Sha1)
+	assert.True(t, errors.Is(err, ErrInvalidHashLength))
+
+	_, err = Verify(strings.NewReader(doc), "dc0be356e8c2ba26e66448d97db76ad050206574", "md5")
+	assert.True(t, errors.Is(err, ErrUnknownAlgorithm))
+}
+
+func TestVerifyStore(t *testing.T) {
+	sha1 := NewSha1OmniBOR()
+	assert.NoError(t, sha1.AddReference([]byte("hello"), nil))
+	other := NewSha1OmniBOR()
+	assert.NoError(t, other.AddReference([]byte("world"), nil))
+	sha256 := NewSha256OmniBOR()
+	assert.NoError(t, sha256.AddReference([]byte("hello"), nil))
+
+	fileStore := NewFileObjectStore(t.TempDir())
+	for _, store := range []ObjectStore{fileStore, NewFileObjectStore(t.TempDir(), WithVerifyOnRead()), NewMemoryStore()} {
+		for _, gb := range []ArtifactTree{sha1, other, sha256} {
+			assert.NoError(t, store.Put(gb.Identity(), []byte(gb.String())))
+		}
+		corrupted, err := VerifyStore(store, Sha1)
+		assert.NoError(t, err)
+		assert.Empty(t, corrupted)
+
+		assert.NoError(t, store.Put(other.Identity(), []byte(sha1.String())))
+		assert.NoError(t, store.Put(sha256.Identity(), []byte(sha1.String())))
+		corrupted, err = VerifyStore(store, Sha1)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{other.Identity()}, corrupted)
+		corrupted, err = VerifyStore(store, Sha256)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{sha256.Identity()}, corrupted)
+	}
+
+	keys, err := fileStore.Keys()
+	assert.NoError(t, err)
+	assert.Len(t, keys, 3)
+
+	_, err = VerifyStore(fileStore, HashAlgorithm("md5"))
+	assert.True(t, errors.Is(err, ErrUnknownAlgorithm))
+
+	corrupted, err := VerifyStore(NewFileObjectStore(t.TempDir()), Sha1)
+	assert.NoError(t, err)
+	assert.Empty(t, corrupted)
+
+	// a store that cannot list its objects
+	_, err = VerifyStore(struct{ ObjectStore }{NewMemoryStore()}, Sha1)
+	assert.Error(t, err)
+}
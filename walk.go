@@ -0,0 +1,340 @@
+package omnibor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errWalkCancelled stops the directory walk once a worker has failed.
+var errWalkCancelled = errors.New("walk cancelled")
+
+// openFile is replaced by tests to simulate files that cannot be read, whatever the privileges of the user.
+var openFile = os.Open
+
+type fileEvent struct {
+	path string
+	name string // path the walk reached the file by, which differs from path when a symbolic link was followed
+	info os.FileInfo
+}
+
+// AddTree walks root, following symbolic links unless disabled by WithFollowSymlinks, and adds a reference
+// for every regular file found, skipping FIFOs, sockets and devices, which cannot be hashed like files and
+// may block when opened. Files are hashed concurrently by a bounded set of workers, see WithWorkers.
+// The first error encountered stops the walk, remaining files are skipped and the error is returned.
+func (srv *omniBor) AddTree(root string, opts ...Option) error {
+	return srv.AddTreeContext(context.Background(), root, opts...)
+}
+
+// AddTreeContext is AddTree with cancellation: once ctx is done the walk stops, files being hashed are
+// abandoned and ctx.Err() is returned after every worker has exited.
+// References added before the cancellation are kept.
+func (srv *omniBor) AddTreeContext(ctx context.Context, root string, opts ...Option) error {
+	o := srv.ingestOptions(opts)
+	walk := func(fn func(fileEvent) error) error {
+		return o.walk(root, fn)
+	}
+	add := func(ctx context.Context, ev fileEvent) (string, int64, error) {
+		identity, err := srv.addFile(ctx, o, ev)
+		return identity, ev.info.Size(), err
+	}
+	return srv.addFiles(ctx, o, walk, add)
+}
+
+// addFiles adds a reference for every file walk calls back with, hashing them with add on o.workers goroutines.
+// add returns the gitoid and size of the file it added, or an empty gitoid if it skipped the file.
+// With o.workers set to zero files are added on the walking goroutine instead, for sources that have to be
+// read in order such as a tar stream.
+func (srv *omniBor) addFiles(ctx context.Context, o *options, walk func(func(fileEvent) error) error,
+	add func(context.Context, fileEvent) (string, int64, error)) error {
+	var metrics Metrics
+	var metricsLock sync.Mutex
+	if o.metrics != nil {
+		start := time.Now()
+		defer func() {
+			metrics.Total = time.Since(start)
+			o.metrics.Files += metrics.Files
+			o.metrics.Bytes += metrics.Bytes
+			o.metrics.Total += metrics.Total
+			o.metrics.Walk += metrics.Walk
+			o.metrics.Hash += metrics.Hash
+		}()
+	}
+	measuredAdd := func(ctx context.Context, ev fileEvent) (string, error) {
+		if o.metrics == nil {
+			identity, _, err := add(ctx, ev)
+			return identity, err
+		}
+		start := time.Now()
+		identity, size, err := add(ctx, ev)
+		elapsed := time.Since(start)
+
+		metricsLock.Lock()
+		defer metricsLock.Unlock()
+		metrics.Hash += elapsed
+		if err == nil {
+			metrics.Files++
+			metrics.Bytes += size
+		}
+		return identity, err
+	}
+
+	events := make(chan fileEvent)
+	done := make(chan struct{})
+
+	var once sync.Once
+	var firstErr error
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			close(done)
+		})
+	}
+
+	var progressLock sync.Mutex
+	added, found := 0, 0
+	progress := func(ev fileEvent, identity string) {
+		if o.progress == nil && o.fileIdentities == nil {
+			return
+		}
+		progressLock.Lock()
+		defer progressLock.Unlock()
+		if o.fileIdentities != nil && identity != "" {
+			o.fileIdentities(ev.name, identity)
+		}
+		if o.progress != nil {
+			added++
+			o.progress(ev.path, added, found)
+		}
+	}
+
+	process := func(ev fileEvent) {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+			return
+		default:
+		}
+		var identity string
+		err := o.hash(func() error {
+			var err error
+			identity, err = measuredAdd(ctx, ev)
+			return err
+		})
+		if err != nil {
+			if !o.skip(err) {
+				fail(err)
+			}
+			return
+		}
+		progress(ev, identity)
+	}
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < o.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ev := range events {
+				process(ev)
+			}
+		}()
+	}
+
+	walkStart := time.Now()
+	err := walk(func(ev fileEvent) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		progressLock.Lock()
+		found++
+		progressLock.Unlock()
+
+		if o.workers == 0 {
+			process(ev)
+			select {
+			case <-done:
+				return errWalkCancelled
+			default:
+				return ctx.Err()
+			}
+		}
+		select {
+		case events <- ev:
+			return nil
+		case <-done:
+			return errWalkCancelled
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+
+	metrics.Walk = time.Since(walkStart)
+
+	close(events)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err != nil && err != errWalkCancelled {
+		fail(err)
+	}
+	if firstErr == nil && len(o.skipped) > 0 {
+		return o.skipped
+	}
+	return firstErr
+}
+
+// walk calls fn for every file below root, honoring the exclude and symlink options.
+// Every directory is walked at most once, however many symbolic links lead to it, which also stops symlink cycles.
+func (o *options) walk(root string, fn func(fileEvent) error) error {
+	visited := make(map[string]bool)
+	return o.walkDir(root, root, root, visited, fn)
+}
+
+// walkDir walks dir, which is shown as display in paths matched against the exclude patterns.
+// display differs from dir when dir was reached through a symbolic link.
+// Unreadable files and directories are skipped if o continues past errors.
+func (o *options) walkDir(root, dir, display string, visited map[string]bool, fn func(fileEvent) error) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if o.skip(err) {
+				return skipEntry(info)
+			}
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		name := filepath.Join(display, rel)
+		if excluded, err := o.excluded(root, name); err != nil || excluded {
+			if err == nil && info.IsDir() {
+				return filepath.SkipDir
+			}
+			return err
+		}
+
+		if info.Mode()&os.ModeSymlink == 0 {
+			if !info.IsDir() {
+				if !info.Mode().IsRegular() {
+					return nil
+				}
+				return fn(fileEvent{path: path, name: name, info: info})
+			}
+			real, err := filepath.EvalSymlinks(path)
+			if err != nil {
+				return err
+			}
+			if visited[real] {
+				return filepath.SkipDir
+			}
+			visited[real] = true
+			return nil
+		}
+
+		if !o.followSymlinks {
+			return fn(fileEvent{path: path, name: name, info: info})
+		}
+		var targetInfo os.FileInfo
+		target, err := filepath.EvalSymlinks(path)
+		if err == nil {
+			targetInfo, err = os.Stat(target)
+		}
+		if err != nil {
+			if o.skip(err) {
+				return nil
+			}
+			return err
+		}
+		if !targetInfo.IsDir() {
+			if !targetInfo.Mode().IsRegular() {
+				return nil
+			}
+			return fn(fileEvent{path: target, name: name, info: targetInfo})
+		}
+		if visited[target] {
+			return nil
+		}
+		return o.walkDir(root, target, name, visited, fn)
+	})
+}
+
+// skipEntry returns what a walk function returns to skip the entry described by info after an error.
+func skipEntry(info os.FileInfo) error {
+	if info != nil && info.IsDir() {
+		return filepath.SkipDir
+	}
+	return nil
+}
+
+// hash runs fn, through the shared hasher pool if there is one.
+func (o *options) hash(fn func() error) error {
+	if o.sharedHasher != nil {
+		return o.sharedHasher.Do(fn)
+	}
+	return fn()
+}
+
+// addFileID adds a reference for length bytes of reader, a file being ingested with o, and returns its gitoid.
+func (srv *omniBor) addFileID(o *options, reader io.Reader, length int64) (string, error) {
+	return srv.addObjectID(KindBlob, reader, nil, length, o.buffers)
+}
+
+// addFile adds a reference for the file of ev. A symbolic link, only handed in when links are not followed,
+// is recorded the way git records it: as a blob holding the link's target path.
+// Files the hash cache of o knows are added without being read. It returns the gitoid of the file.
+func (srv *omniBor) addFile(ctx context.Context, o *options, ev fileEvent) (string, error) {
+	path, info := ev.path, ev.info
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(path)
+		if err != nil {
+			return "", err
+		}
+		return srv.addFileID(o, strings.NewReader(target), int64(len(target)))
+	}
+
+	if o.hashCache != nil {
+		if identity, ok := o.hashCache.Lookup(path, info); ok && srv.validateReference(identity, nil) == nil {
+			srv.lock.Lock()
+			srv.appendDistinct(reference{identity: identity})
+			srv.lock.Unlock()
+			return identity, nil
+		}
+	}
+
+	f, err := openFile(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	identity, err := srv.addFileID(o, &contextReader{ctx: ctx, r: f}, info.Size())
+	if err != nil {
+		return "", withPath(err, path)
+	}
+	if o.hashCache != nil {
+		o.hashCache.Record(path, info, identity)
+	}
+	return identity, nil
+}
+
+// contextReader fails every Read with the context's error once it is done, aborting hashing in flight.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
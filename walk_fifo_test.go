@@ -0,0 +1,45 @@
+//go:build !windows
+
+package omnibor
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddTreeSkipsSpecialFiles(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "a/hello", "hello")
+	writeTestFile(t, root, "b/world", "world")
+	require.NoError(t, syscall.Mkfifo(filepath.Join(root, "fifo"), 0644))
+	socket, err := net.Listen("unix", filepath.Join(root, "socket"))
+	require.NoError(t, err)
+	defer socket.Close()
+	require.NoError(t, os.Symlink(filepath.Join(root, "fifo"), filepath.Join(root, "link")))
+
+	// opening the FIFO blocks until a writer shows up, which no context can cancel
+	for name, add := range map[string]func(ArtifactTree) error{
+		"AddTree": func(gb ArtifactTree) error { return gb.AddTree(root) },
+		"AddFS":   func(gb ArtifactTree) error { return gb.AddFS(os.DirFS(root), ".") },
+	} {
+		gb := NewSha1OmniBOR()
+		done := make(chan error, 1)
+		go func() {
+			done <- add(gb)
+		}()
+		select {
+		case err := <-done:
+			require.NoError(t, err, name)
+			assert.Equal(t, "dc0be356e8c2ba26e66448d97db76ad050206574", gb.Identity(), name)
+		case <-time.After(10 * time.Second):
+			t.Fatalf("%s blocked on a special file", name)
+		}
+	}
+}
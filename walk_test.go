@@ -0,0 +1,423 @@
+package omnibor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestFile(t *testing.T, dir, name, content string) {
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+// createTree lays out root/a/hello, root/b/world and a symlink root/c pointing at a directory outside root containing hello2.
+func createTree(t *testing.T) string {
+	root := t.TempDir()
+	external := t.TempDir()
+
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "a"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "b"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a", "hello"), []byte("hello"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "b", "world"), []byte("world"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(external, "hello2"), []byte("hello2"), 0644))
+	require.NoError(t, os.Symlink(external, filepath.Join(root, "c")))
+
+	return root
+}
+
+func TestAddTree(t *testing.T) {
+	root := createTree(t)
+
+	gb := NewSha1OmniBOR()
+	err := gb.AddTree(root)
+	assert.NoError(t, err)
+
+	expected := "blob 04fea06420ca60892f73becee3614f6d023a4b7f\n" +
+		"blob 23294b0610492cf55c1c4835216f20d376a287dd\n" +
+		"blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\n"
+	assert.Equal(t, expected, gb.String())
+}
+
+func TestAddTreeWorkers(t *testing.T) {
+	root := createTree(t)
+
+	gb1 := NewSha1OmniBOR()
+	assert.NoError(t, gb1.AddTree(root, WithWorkers(1)))
+
+	gb2 := NewSha1OmniBOR()
+	assert.NoError(t, gb2.AddTree(root, WithWorkers(4)))
+
+	assert.Equal(t, gb1.String(), gb2.String())
+	assert.Equal(t, gb1.Identity(), gb2.Identity())
+}
+
+func TestAddTreeMissingRoot(t *testing.T) {
+	gb := NewSha1OmniBOR()
+	err := gb.AddTree(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+	assert.Equal(t, "", gb.String())
+}
+
+func TestAddTreeBrokenSymlink(t *testing.T) {
+	root := createTree(t)
+	require.NoError(t, os.Symlink(filepath.Join(root, "missing"), filepath.Join(root, "d")))
+
+	gb := NewSha1OmniBOR()
+	err := gb.AddTree(root)
+	assert.Error(t, err)
+}
+
+func TestAddTreeContextCancel(t *testing.T) {
+	root := t.TempDir()
+	const files = 2000
+	for i := 0; i < files; i++ {
+		writeTestFile(t, root, filepath.Join(fmt.Sprintf("%02d", i%50), fmt.Sprintf("file%d", i)), fmt.Sprintf("content %d", i))
+	}
+
+	before := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	gb := NewSha1OmniBOR()
+	go func() {
+		for gb.Len() < 10 {
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+	}()
+
+	err := gb.AddTreeContext(ctx, root, WithWorkers(4))
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Less(t, gb.Len(), files)
+
+	// every worker has exited by the time AddTreeContext returns, only the canceller may still be winding down
+	assertGoroutinesExited(t, before)
+}
+
+// assertGoroutinesExited waits up to a second for the number of goroutines to drop back to before.
+func assertGoroutinesExited(t *testing.T, before int) {
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), before)
+}
+
+func TestAddTreeErrorNoLeak(t *testing.T) {
+	root := t.TempDir()
+	const files = 2000
+	for i := 0; i < files; i++ {
+		writeTestFile(t, root, filepath.Join(fmt.Sprintf("%02d", i%50), fmt.Sprintf("file%d", i)), fmt.Sprintf("content %d", i))
+	}
+
+	before := runtime.NumGoroutine()
+
+	// a broken link fails the walk itself
+	link := filepath.Join(root, "25", "link")
+	require.NoError(t, os.Symlink(filepath.Join(root, "missing"), link))
+	err := NewSha1OmniBOR().AddTree(root, WithWorkers(8))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "missing")
+	assertGoroutinesExited(t, before)
+	require.NoError(t, os.Remove(link))
+
+	// an unreadable file fails a worker mid-walk
+	writeTestFile(t, root, filepath.Join("25", "unreadable"), "unreadable")
+	failOpen(t, "unreadable")
+	gb := NewSha1OmniBOR()
+	err = gb.AddTree(root, WithWorkers(8))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unreadable")
+	assert.Less(t, gb.Len(), files)
+	assertGoroutinesExited(t, before)
+}
+
+// failOpen makes AddTree fail to open every file named name for the rest of the test.
+func failOpen(t *testing.T, name string) {
+	t.Cleanup(func() {
+		openFile = os.Open
+	})
+	openFile = func(path string) (*os.File, error) {
+		if filepath.Base(path) == name {
+			return nil, &os.PathError{Op: "open", Path: path, Err: os.ErrPermission}
+		}
+		return os.Open(path)
+	}
+}
+
+func TestAddTreeContextCancelled(t *testing.T) {
+	root := createTree(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	gb := NewSha1OmniBOR()
+	err := gb.AddTreeContext(ctx, root)
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, 0, gb.Len())
+}
+
+func TestAddTreeExclude(t *testing.T) {
+	root := createTree(t)
+	writeTestFile(t, root, filepath.Join("node_modules", "dep", "index.js"), "independent")
+	writeTestFile(t, root, filepath.Join("a", "node_modules", "opaque"), "opaque")
+	writeTestFile(t, root, filepath.Join("b", "world.o"), "hello2")
+
+	gb := NewSha1OmniBOR()
+	err := gb.AddTree(root, WithExclude("node_modules"), WithExclude("b/*.o", "c"))
+	assert.NoError(t, err)
+
+	expected := "blob 04fea06420ca60892f73becee3614f6d023a4b7f\n" +
+		"blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\n"
+	assert.Equal(t, expected, gb.String())
+
+	// patterns relative to the root only apply at that level
+	gb = NewSha1OmniBOR()
+	err = gb.AddTree(root, WithExclude("*.o", "node_modules/dep"))
+	assert.NoError(t, err)
+	assert.False(t, gb.Contains("be78cc5602c5457f144a67e574b8f98b9dc2a1a0"))
+	assert.True(t, gb.Contains("32898208a218272b0fa7549f60951d4eed2ed830"))
+	assert.Equal(t, 4, gb.Len())
+
+	err = NewSha1OmniBOR().AddTree(root, WithExclude("[a-"))
+	assert.True(t, errors.Is(err, filepath.ErrBadPattern))
+}
+
+func TestAddTreeSymlinkCycle(t *testing.T) {
+	root := createTree(t)
+	require.NoError(t, os.Symlink(".", filepath.Join(root, "a", "loop")))
+	require.NoError(t, os.Symlink(root, filepath.Join(root, "b", "root")))
+
+	gb := NewSha1OmniBOR()
+	assert.NoError(t, gb.AddTree(root))
+	expected := "blob 04fea06420ca60892f73becee3614f6d023a4b7f\n" +
+		"blob 23294b0610492cf55c1c4835216f20d376a287dd\n" +
+		"blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\n"
+	assert.Equal(t, expected, gb.String())
+
+	dot, err := GitOID(strings.NewReader("."), 1, Sha1)
+	require.NoError(t, err)
+	gb = NewSha1OmniBOR()
+	assert.NoError(t, gb.AddTree(root, WithFollowSymlinks(false)))
+	assert.True(t, gb.Contains(dot))
+	assert.Equal(t, 5, gb.Len())
+}
+
+func TestAddTreeNoFollowSymlinks(t *testing.T) {
+	root := t.TempDir()
+	external := filepath.Join(t.TempDir(), "hello2")
+	writeTestFile(t, root, "hello", "hello")
+	require.NoError(t, os.WriteFile(external, []byte("hello2"), 0644))
+	require.NoError(t, os.Symlink(external, filepath.Join(root, "link")))
+
+	gb := NewSha1OmniBOR()
+	assert.NoError(t, gb.AddTree(root))
+	assert.Equal(t, "blob 23294b0610492cf55c1c4835216f20d376a287dd\n"+
+		"blob b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0\n", gb.String())
+
+	link, err := GitOID(strings.NewReader(external), int64(len(external)), Sha1)
+	require.NoError(t, err)
+	gb = NewSha1OmniBOR()
+	assert.NoError(t, gb.AddTree(root, WithFollowSymlinks(false)))
+	assert.False(t, gb.Contains("23294b0610492cf55c1c4835216f20d376a287dd"))
+	assert.True(t, gb.Contains(link))
+	assert.Equal(t, 2, gb.Len())
+
+	// a broken link is recorded as is
+	require.NoError(t, os.Remove(external))
+	gb = NewSha1OmniBOR()
+	assert.NoError(t, gb.AddTree(root, WithFollowSymlinks(false)))
+	assert.True(t, gb.Contains(link))
+	assert.Error(t, NewSha1OmniBOR().AddTree(root))
+}
+
+func TestAddTreeProgress(t *testing.T) {
+	root := createTree(t)
+	writeTestFile(t, root, "a/independent", "independent")
+
+	var calls, lastDone, lastTotal int
+	var paths []string
+	gb := NewSha1OmniBOR()
+	err := gb.AddTree(root, WithWorkers(3), WithProgress(func(path string, done, total int) {
+		calls++
+		assert.Equal(t, calls, done)
+		assert.LessOrEqual(t, done, total)
+		paths = append(paths, path)
+		lastDone, lastTotal = done, total
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, gb.Len(), calls)
+	assert.Equal(t, 4, lastDone)
+	assert.Equal(t, 4, lastTotal)
+	assert.Contains(t, paths, filepath.Join(root, "a", "independent"))
+}
+
+// mapCache is a HashCache keyed by path alone, trusting every entry.
+type mapCache struct {
+	lock    sync.Mutex
+	entries map[string]string
+	lookups int
+}
+
+func (c *mapCache) Lookup(path string, info os.FileInfo) (string, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.lookups++
+	identity, ok := c.entries[path]
+	return identity, ok
+}
+
+func (c *mapCache) Record(path string, info os.FileInfo, identity string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.entries[path] = identity
+}
+
+func TestAddTreeHashCache(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "hello", "hello")
+	writeTestFile(t, root, "world", "world")
+	cache := &mapCache{entries: make(map[string]string)}
+
+	gb := NewSha1OmniBOR()
+	require.NoError(t, gb.AddTree(root, WithHashCache(cache)))
+	assert.Equal(t, "dc0be356e8c2ba26e66448d97db76ad050206574", gb.Identity())
+	assert.Equal(t, map[string]string{
+		filepath.Join(root, "hello"): "b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0",
+		filepath.Join(root, "world"): "04fea06420ca60892f73becee3614f6d023a4b7f",
+	}, cache.entries)
+
+	// the cache is trusted, the changed content is not read
+	writeTestFile(t, root, "world", "hello2")
+	gb = NewSha1OmniBOR()
+	require.NoError(t, gb.AddTree(root, WithHashCache(cache)))
+	assert.Equal(t, "dc0be356e8c2ba26e66448d97db76ad050206574", gb.Identity())
+	assert.Equal(t, 4, cache.lookups)
+
+	// gitoids of another algorithm are hashed again
+	gb = NewSha256OmniBOR()
+	require.NoError(t, gb.AddTree(root, WithHashCache(cache)))
+	assert.True(t, gb.Contains("1861fbb8d1e47ae6328232968bac77acfd7c9afa2f179afbcdae3fd1b0658a60"))
+	assert.Equal(t, "8aec4e4876f854f688d0ebfc8f37598f38e5fd6903cccc850ca36591175aeb60", cache.entries[filepath.Join(root, "hello")])
+}
+
+func TestAddTreeMetrics(t *testing.T) {
+	root := t.TempDir()
+	var size int64
+	for i := 0; i < 50; i++ {
+		content := strings.Repeat("x", i*100)
+		writeTestFile(t, root, fmt.Sprintf("dir%d/file%d", i%4, i), content)
+		size += int64(len(content))
+	}
+
+	var metrics Metrics
+	gb := NewSha1OmniBOR()
+	require.NoError(t, gb.AddTree(root, WithMetrics(&metrics), WithWorkers(4)))
+	assert.Equal(t, 50, metrics.Files)
+	assert.Equal(t, size, metrics.Bytes)
+	assert.Greater(t, metrics.Hash, time.Duration(0))
+	assert.Greater(t, metrics.Walk, time.Duration(0))
+	assert.GreaterOrEqual(t, metrics.Total, metrics.Walk)
+
+	// figures accumulate across calls
+	fsys := fstest.MapFS{"hello": {Data: []byte("hello")}}
+	require.NoError(t, NewSha1OmniBOR().AddFS(fsys, ".", WithMetrics(&metrics)))
+	assert.Equal(t, 51, metrics.Files)
+	assert.Equal(t, size+5, metrics.Bytes)
+}
+
+func TestAddTreeFileIdentities(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "hello", "hello")
+	writeTestFile(t, root, "dir/world", "world")
+	require.NoError(t, os.Symlink(filepath.Join(root, "dir"), filepath.Join(root, "link")))
+	require.NoError(t, os.Symlink(filepath.Join(root, "hello"), filepath.Join(root, "alias")))
+
+	identities := make(map[string]string)
+	gb := NewSha1OmniBOR()
+	require.NoError(t, gb.AddTree(root, WithFileIdentities(func(path, identity string) {
+		identities[path] = identity
+	})))
+	assert.Equal(t, map[string]string{
+		filepath.Join(root, "alias"):     "b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0",
+		filepath.Join(root, "hello"):     "b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0",
+		filepath.Join(root, "dir/world"): "04fea06420ca60892f73becee3614f6d023a4b7f",
+	}, identities)
+}
+
+func TestAddTreeContinueOnError(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "a/hello", "hello")
+	writeTestFile(t, root, "b/world", "world")
+	writeTestFile(t, root, "a/unreadable", "unreadable")
+	failOpen(t, "unreadable")
+	require.NoError(t, os.Symlink(filepath.Join(root, "missing"), filepath.Join(root, "b", "link")))
+
+	gb := NewSha1OmniBOR()
+	err := gb.AddTree(root, WithContinueOnError(true), WithWorkers(2))
+	var skipped FileErrors
+	require.True(t, errors.As(err, &skipped), "%v", err)
+	assert.Len(t, skipped, 2)
+	assert.Contains(t, err.Error(), "unreadable")
+	assert.Contains(t, err.Error(), "missing")
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+	assert.True(t, errors.Is(err, os.ErrPermission))
+	assert.Equal(t, "dc0be356e8c2ba26e66448d97db76ad050206574", gb.Identity())
+
+	// failing fast is the default
+	err = NewSha1OmniBOR().AddTree(root)
+	require.Error(t, err)
+	assert.False(t, errors.As(err, &skipped))
+}
+
+func TestAddTreeBufferSize(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "large", strings.Repeat("hello world\n", 10000))
+	writeTestFile(t, root, "hello", "hello")
+
+	expected := NewSha1OmniBOR()
+	require.NoError(t, expected.AddTree(root))
+
+	for _, size := range []int{0, 1, 7, 1 << 20} {
+		gb := NewSha1OmniBOR()
+		require.NoError(t, gb.AddTree(root, WithBufferSize(size)), size)
+		assert.Equal(t, expected.String(), gb.String(), size)
+	}
+}
+
+func BenchmarkAddTreeBufferSize(b *testing.B) {
+	const size = 256 << 20
+	root := filepath.Dir(largeFile(b, size).Name())
+
+	for _, bm := range []struct {
+		name string
+		opts []Option
+	}{
+		{"default", nil},
+		{"1MiB", []Option{WithBufferSize(1 << 20)}},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			b.SetBytes(size)
+			for i := 0; i < b.N; i++ {
+				if err := NewSha256OmniBOR().AddTree(root, bm.opts...); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package omnibor
+
+import (
+	"archive/zip"
+	"context"
+	"io"
+	"os"
+	"path"
+)
+
+// maxZipLinks bounds how many symbolic links are followed to resolve one zip entry, as the kernel bounds path lookups.
+const maxZipLinks = 40
+
+// AddZip adds a reference for every file of the zip archive of the given size read from r, which includes jar
+// and wheel files, as AddTree would for the extracted archive. Entries are hashed concurrently like files by AddTree,
+// so r must support concurrent reads as *os.File and *bytes.Reader do. Directories are skipped and WithExclude is
+// matched against entry names. Archives nested in the archive are shallow: they are hashed as files like any other,
+// their entries are not added.
+// A symbolic link, unless disabled by WithFollowSymlinks, adds the reference of the file it points to if that file is
+// in the archive and is skipped otherwise. A symbolic link that is not followed is referenced like git records it,
+// as a blob holding the link's target path.
+func (srv *omniBor) AddZip(r io.ReaderAt, size int64, opts ...Option) error {
+	o := srv.ingestOptions(opts)
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+
+	byName := make(map[string]*zip.File, len(zr.File))
+	byHeader := make(map[*zip.FileHeader]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		byName[path.Clean(f.Name)] = f
+		byHeader[&f.FileHeader] = f
+	}
+
+	walk := func(fn func(fileEvent) error) error {
+		for _, f := range zr.File {
+			info := f.FileInfo()
+			if !info.Mode().IsRegular() && info.Mode()&os.ModeSymlink == 0 {
+				continue
+			}
+			if excluded, err := o.excludedEntry(f.Name); err != nil {
+				return err
+			} else if excluded {
+				continue
+			}
+			if err := fn(fileEvent{path: f.Name, name: f.Name, info: info}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	add := func(ctx context.Context, ev fileEvent) (string, int64, error) {
+		f := byHeader[ev.info.Sys().(*zip.FileHeader)]
+		if o.followSymlinks {
+			if f = resolveZipLinks(f, byName); f == nil {
+				return "", 0, nil
+			}
+		}
+		identity, size, err := srv.addZipFile(ctx, o, f)
+		return identity, size, withPath(err, ev.path)
+	}
+	return srv.addFiles(context.Background(), o, walk, add)
+}
+
+// resolveZipLinks returns the file f points to if it is a symbolic link, following links to links,
+// or nil if the target is not in byName, the files of the archive by name.
+func resolveZipLinks(f *zip.File, byName map[string]*zip.File) *zip.File {
+	for i := 0; i <= maxZipLinks; i++ {
+		if f.Mode()&os.ModeSymlink == 0 {
+			return f
+		}
+		target, err := readZipLink(f)
+		if err != nil {
+			return nil
+		}
+		if f = byName[path.Join(path.Dir(f.Name), target)]; f == nil {
+			return nil
+		}
+	}
+	return nil
+}
+
+// readZipLink returns the target path of the symbolic link f, which zip stores as the entry's content.
+func readZipLink(f *zip.File) (string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	target, err := io.ReadAll(io.LimitReader(rc, 4096))
+	return string(target), err
+}
+
+// addZipFile adds a reference for the content of f and returns its gitoid and size.
+func (srv *omniBor) addZipFile(ctx context.Context, o *options, f *zip.File) (string, int64, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", 0, err
+	}
+	defer rc.Close()
+
+	size := int64(f.UncompressedSize64)
+	identity, err := srv.addFileID(o, &contextReader{ctx: ctx, r: rc}, size)
+	return identity, size, err
+}
@@ -0,0 +1,87 @@
+package omnibor
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type zipEntry struct {
+	name    string
+	content string
+	mode    os.FileMode
+}
+
+func buildZip(t *testing.T, entries ...zipEntry) *bytes.Reader {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, entry := range entries {
+		header := &zip.FileHeader{Name: entry.name, Method: zip.Deflate}
+		header.SetMode(entry.mode)
+		w, err := zw.CreateHeader(header)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(entry.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestAddZip(t *testing.T) {
+	archive := buildZip(t,
+		zipEntry{name: "src/", mode: os.ModeDir | 0755},
+		zipEntry{name: "src/a/hello", content: "hello", mode: 0644},
+		zipEntry{name: "src/b/world", content: "world", mode: 0644},
+		zipEntry{name: "src/vendor/hello2", content: "hello2", mode: 0644},
+	)
+
+	gb := NewSha1OmniBOR()
+	require.NoError(t, gb.AddZip(archive, archive.Size(), WithExclude("vendor"), WithWorkers(2)))
+	assert.Equal(t, "dc0be356e8c2ba26e66448d97db76ad050206574", gb.Identity())
+
+	gb = NewSha256OmniBOR()
+	require.NoError(t, gb.AddZip(archive, archive.Size(), WithExclude("vendor")))
+	assert.Equal(t, "e32e7e7761709be17ef573556a82960d489ddf0092424f7db1c91d8363dde822", gb.Identity())
+}
+
+func TestAddZipNestedAndLinks(t *testing.T) {
+	nested := buildZip(t, zipEntry{name: "inner", content: "independent", mode: 0644})
+	content := make([]byte, nested.Size())
+	_, err := nested.ReadAt(content, 0)
+	require.NoError(t, err)
+
+	archive := buildZip(t,
+		zipEntry{name: "hello", content: "hello", mode: 0644},
+		zipEntry{name: "lib/nested.jar", content: string(content), mode: 0644},
+		zipEntry{name: "lib/link", content: "../hello", mode: os.ModeSymlink | 0777},
+		zipEntry{name: "dangling", content: "missing", mode: os.ModeSymlink | 0777},
+	)
+
+	// the nested archive is a single file, the link resolves to hello and the dangling link is skipped
+	expected := NewSha1OmniBOR()
+	require.NoError(t, expected.AddReference([]byte("hello"), nil))
+	require.NoError(t, expected.AddReference([]byte("hello"), nil))
+	require.NoError(t, expected.AddReference(content, nil))
+
+	gb := NewSha1OmniBOR()
+	require.NoError(t, gb.AddZip(archive, archive.Size()))
+	assert.Equal(t, expected.String(), gb.String())
+
+	expected = NewSha1OmniBOR()
+	for _, content := range [][]byte{[]byte("hello"), content, []byte("../hello"), []byte("missing")} {
+		require.NoError(t, expected.AddReference(content, nil))
+	}
+	gb = NewSha1OmniBOR()
+	require.NoError(t, gb.AddZip(archive, archive.Size(), WithFollowSymlinks(false)))
+	assert.Equal(t, expected.String(), gb.String())
+}
+
+func TestAddZipInvalid(t *testing.T) {
+	gb := NewSha1OmniBOR()
+	assert.Error(t, gb.AddZip(bytes.NewReader([]byte("not a zip")), 9))
+	assert.True(t, gb.IsEmpty())
+}